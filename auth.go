@@ -10,15 +10,13 @@ import (
 
 // vaultAuth holds the Vault Auth response from server
 type vaultAuth struct {
-	ClientToken string   `json:"client_token"`
-	Accessor    string   `json:"accessor"`
-	Policies    []string `json:"policies"`
-	Metadata    struct {
-		RoleName string `json:"role_name"`
-	} `json:"metadata"`
-	LeaseDuration int    `json:"lease_duration"`
-	Renewable     bool   `json:"renewable"`
-	EntityID      string `json:"entity_id"`
+	ClientToken   string            `json:"client_token"`
+	Accessor      string            `json:"accessor"`
+	Policies      []string          `json:"policies"`
+	Metadata      map[string]string `json:"metadata"`
+	LeaseDuration int               `json:"lease_duration"`
+	Renewable     bool              `json:"renewable"`
+	EntityID      string            `json:"entity_id"`
 }
 
 // renew the client's token, launched at client creation time as a go routine
@@ -27,7 +25,7 @@ func (c *Client) renewToken() {
 	jsonToken := make(map[string]string)
 
 	for {
-		duration := c.token.TTL - 2
+		duration := c.GetTokenInfo().TTL - 2
 		time.Sleep(time.Second * time.Duration(duration))
 
 		url := c.address.String() + "/v1/auth/token/renew-self"
@@ -88,6 +86,7 @@ func (c *Client) setTokenFromAppRole() error {
 	c.withLockContext(func() {
 		c.token.ID = vaultData.ClientToken
 	})
+	c.invalidateTokenSecrets()
 
 	if err = c.setTokenInfo(); err != nil {
 		return errors.Wrap(errors.WithStack(err), errInfo())
@@ -124,9 +123,12 @@ func (c *Client) setTokenInfo() error {
 		return err
 	}
 	c.withLockContext(func() {
+		previous := c.token
 		c.token = &tokenInfo
 		c.isAuthenticated = true
-
+		if previous.changed(c.token) {
+			c.notifyTokenChanged()
+		}
 	})
 	return nil
 }
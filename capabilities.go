@@ -0,0 +1,95 @@
+package vaultlib
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// Capabilities returns the client's token capabilities on each of the given paths.
+//
+// KV v2 secret paths are translated to their "data/" path, as done by GetSecret.
+// Paths not belonging to a secret engine (ie sys/, auth/) are checked as is.
+//
+// Returns a map of the given paths to their capabilities (ie "read", "update", "deny").
+func (c *Client) Capabilities(paths ...string) (map[string][]string, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("At least one path must be specified")
+	}
+
+	mounts, err := c.getSecretMounts()
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	apiPaths := make([]string, len(paths))
+	for i, path := range paths {
+		apiPaths[i] = path
+		if kvVersion, kvName := kvInfoFromMounts(mounts, path); kvVersion != "" {
+			apiPaths[i] = kvAPIPath(kvVersion, kvName, path)
+		}
+	}
+
+	url := c.address.String() + "/v1/sys/capabilities-self"
+	req, err := c.newRequest("POST", url)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if err = req.setJSONBody(map[string][]string{"paths": apiPaths}); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	rsp, err := req.execute()
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	// data holds one entry per path, plus a "capabilities" entry when a single path is requested
+	raw := make(map[string]json.RawMessage)
+	if err = json.Unmarshal([]byte(rsp.Data), &raw); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	capabilities := make(map[string][]string, len(paths))
+	for i, path := range paths {
+		var pathCapabilities []string
+		pathRaw, ok := raw[apiPaths[i]]
+		if !ok {
+			pathRaw = raw["capabilities"]
+		}
+		if err = json.Unmarshal(pathRaw, &pathCapabilities); err != nil {
+			return nil, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		capabilities[path] = pathCapabilities
+	}
+	return capabilities, nil
+}
+
+// CanRead returns true if the client's token can read the given path
+func (c *Client) CanRead(path string) (bool, error) {
+	return c.hasCapability(path, "read")
+}
+
+// CanWrite returns true if the client's token can create or update the given path
+func (c *Client) CanWrite(path string) (bool, error) {
+	return c.hasCapability(path, "create", "update")
+}
+
+// hasCapability returns true if the token has any of the wanted capabilities (or root) on the path
+func (c *Client) hasCapability(path string, wanted ...string) (bool, error) {
+	capabilities, err := c.Capabilities(path)
+	if err != nil {
+		return false, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	for _, capability := range capabilities[path] {
+		if capability == "root" {
+			return true, nil
+		}
+		for _, w := range wanted {
+			if capability == w {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
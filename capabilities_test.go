@@ -0,0 +1,76 @@
+package vaultlib
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestClient_Capabilities(t *testing.T) {
+	_ = os.Unsetenv("VAULT_TOKEN")
+	conf := NewConfig()
+	conf.AppRoleCredentials.RoleID = vaultRoleID
+	conf.AppRoleCredentials.SecretID = vaultSecretID
+	vc, err := NewClient(conf)
+	if err != nil {
+		t.Errorf("Failed to get vault cli %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		paths   []string
+		want    map[string][]string
+		wantErr bool
+	}{
+		{"single", []string{"kv_v1/path/my-secret"}, map[string][]string{"kv_v1/path/my-secret": {"list", "read"}}, false},
+		{"kvv2AndSys", []string{"kv_v2/path/my-secret", "sys/health", "not-mounted/secret"},
+			map[string][]string{"kv_v2/path/my-secret": {"list", "read"}, "sys/health": {"read", "sudo"}, "not-mounted/secret": {"deny"}}, false},
+		{"noPath", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := vc.Capabilities(tt.paths...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Client.Capabilities() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Client.Capabilities() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_CanReadCanWrite(t *testing.T) {
+	_ = os.Unsetenv("VAULT_TOKEN")
+	conf := NewConfig()
+	conf.AppRoleCredentials.RoleID = vaultRoleID
+	conf.AppRoleCredentials.SecretID = vaultSecretID
+	vc, _ := NewClient(conf)
+	conf.Token = "my-dev-root-vault-token"
+	rootCli, _ := NewClient(conf)
+
+	tests := []struct {
+		name      string
+		cli       *Client
+		path      string
+		wantRead  bool
+		wantWrite bool
+	}{
+		{"appRoleKV", vc, "kv_v2/path/my-secret", true, false},
+		{"appRoleNoAccess", vc, "not-mounted/secret", false, false},
+		{"root", rootCli, "kv_v2/path/my-secret", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotRead, err := tt.cli.CanRead(tt.path)
+			if err != nil || gotRead != tt.wantRead {
+				t.Errorf("Client.CanRead() = %v, error = %v, want %v", gotRead, err, tt.wantRead)
+			}
+			gotWrite, err := tt.cli.CanWrite(tt.path)
+			if err != nil || gotWrite != tt.wantWrite {
+				t.Errorf("Client.CanWrite() = %v, error = %v, want %v", gotWrite, err, tt.wantWrite)
+			}
+		})
+	}
+}
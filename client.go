@@ -14,8 +14,10 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"net/url"
+	"reflect"
 	"sync"
 	"time"
 
@@ -29,33 +31,190 @@ type Client struct {
 	httpClient         *http.Client
 	appRoleCredentials *AppRoleCredentials
 	token              *VaultTokenInfo
+	tokenFile          *tokenFile
+	tokenSubscribers   map[chan *VaultTokenInfo]struct{}
+	secretCache        *SecretCache
 	namespace          string
 	status             string
 	isAuthenticated    bool
 }
 
-// VaultTokenInfo holds the Vault token information
+// VaultTokenInfo holds the Vault token information.
+//
+// TTLs and times are expressed in seconds as returned by Vault, use the
+// helper methods to get them as time.Duration or time.Time.
+//
+// ExpireTime and Meta stay untyped for compatibility with the existing callers:
+// ExpireTime holds the RFC3339 string returned by Vault (nil if the token never
+// expires) and Meta the decoded JSON object.
 type VaultTokenInfo struct {
-	Accessor       string      `json:"accessor"`
-	CreationTime   int         `json:"creation_time"`
-	CreationTTL    int         `json:"creation_ttl"`
-	DisplayName    string      `json:"display_name"`
-	EntityID       string      `json:"entity_id"`
-	ExpireTime     interface{} `json:"expire_time"`
-	ExplicitMaxTTL int         `json:"explicit_max_ttl"`
-	ID             string      `json:"id"`
-	IssueTime      time.Time   `json:"issue_time"`
-	Meta           interface{} `json:"meta"`
-	NumUses        int         `json:"num_uses"`
-	Orphan         bool        `json:"orphan"`
-	Path           string      `json:"path"`
-	Policies       []string    `json:"policies"`
-	Renewable      bool        `json:"renewable"`
-	TTL            int         `json:"ttl"`
-	Type           string      `json:"type"`
+	Accessor                  string              `json:"accessor"`
+	CreationTime              int                 `json:"creation_time"`
+	CreationTTL               int                 `json:"creation_ttl"`
+	DisplayName               string              `json:"display_name"`
+	EntityID                  string              `json:"entity_id"`
+	ExpireTime                interface{}         `json:"expire_time"` // kept untyped for compatibility, use Expiration
+	ExplicitMaxTTL            int                 `json:"explicit_max_ttl"`
+	ExternalNamespacePolicies map[string][]string `json:"external_namespace_policies"`
+	ID                        string              `json:"id"`
+	IdentityPolicies          []string            `json:"identity_policies"`
+	IssueTime                 time.Time           `json:"issue_time"`
+	LastRenewal               *time.Time          `json:"last_renewal"`
+	LastRenewalTime           int                 `json:"last_renewal_time"`
+	Meta                      interface{}         `json:"meta"` // kept untyped for compatibility, use Metadata
+	NumUses                   int                 `json:"num_uses"`
+	Orphan                    bool                `json:"orphan"`
+	Path                      string              `json:"path"`
+	Period                    int                 `json:"period"`
+	Policies                  []string            `json:"policies"`
+	Renewable                 bool                `json:"renewable"`
+	TTL                       int                 `json:"ttl"`
+	Type                      string              `json:"type"`
+}
+
+// TTLDuration returns the token TTL at lookup time
+func (t *VaultTokenInfo) TTLDuration() time.Duration {
+	return time.Duration(t.TTL) * time.Second
+}
+
+// CreationTTLDuration returns the token TTL at creation time
+func (t *VaultTokenInfo) CreationTTLDuration() time.Duration {
+	return time.Duration(t.CreationTTL) * time.Second
+}
+
+// ExplicitMaxTTLDuration returns the token explicit max TTL (0 if none)
+func (t *VaultTokenInfo) ExplicitMaxTTLDuration() time.Duration {
+	return time.Duration(t.ExplicitMaxTTL) * time.Second
+}
+
+// PeriodDuration returns the period of a periodic token (0 if not periodic)
+func (t *VaultTokenInfo) PeriodDuration() time.Duration {
+	return time.Duration(t.Period) * time.Second
+}
+
+// Created returns the token creation time
+func (t *VaultTokenInfo) Created() time.Time {
+	return time.Unix(int64(t.CreationTime), 0)
+}
+
+// Expiration returns the token expire time, nil if the token never expires (ie root token)
+func (t *VaultTokenInfo) Expiration() *time.Time {
+	var expireTime time.Time
+	switch v := t.ExpireTime.(type) {
+	case string:
+		var err error
+		if expireTime, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			return nil
+		}
+	case time.Time:
+		expireTime = v
+	case *time.Time:
+		if v == nil {
+			return nil
+		}
+		expireTime = *v
+	default:
+		return nil
+	}
+	return &expireTime
+}
+
+// Metadata returns the token metadata
+func (t *VaultTokenInfo) Metadata() map[string]string {
+	var meta map[string]string
+	switch v := t.Meta.(type) {
+	case map[string]interface{}:
+		meta = make(map[string]string, len(v))
+		for k, e := range v {
+			meta[k], _ = e.(string)
+		}
+	case map[string]string:
+		meta = make(map[string]string, len(v))
+		for k, e := range v {
+			meta[k] = e
+		}
+	}
+	return meta
+}
+
+// TimeRemaining returns the duration until the token expires.
+//
+// Returns the maximum duration for tokens that never expire (ie root token).
+func (t *VaultTokenInfo) TimeRemaining() time.Duration {
+	expireTime := t.Expiration()
+	if expireTime == nil {
+		return time.Duration(math.MaxInt64)
+	}
+	remaining := time.Until(*expireTime)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// copy returns a deep copy of the token information
+func (t *VaultTokenInfo) copy() *VaultTokenInfo {
+	if t == nil {
+		return nil
+	}
+	cp := *t
+	if expireTime, ok := t.ExpireTime.(*time.Time); ok && expireTime != nil {
+		cp.ExpireTime = t.Expiration()
+	}
+	if t.LastRenewal != nil {
+		lastRenewal := *t.LastRenewal
+		cp.LastRenewal = &lastRenewal
+	}
+	if t.ExternalNamespacePolicies != nil {
+		cp.ExternalNamespacePolicies = make(map[string][]string, len(t.ExternalNamespacePolicies))
+		for ns, policies := range t.ExternalNamespacePolicies {
+			cp.ExternalNamespacePolicies[ns] = append([]string(nil), policies...)
+		}
+	}
+	switch meta := t.Meta.(type) {
+	case map[string]interface{}:
+		cpMeta := make(map[string]interface{}, len(meta))
+		for k, v := range meta {
+			cpMeta[k] = v
+		}
+		cp.Meta = cpMeta
+	case map[string]string:
+		cp.Meta = t.Metadata()
+	}
+	if t.IdentityPolicies != nil {
+		cp.IdentityPolicies = append([]string(nil), t.IdentityPolicies...)
+	}
+	if t.Policies != nil {
+		cp.Policies = append([]string(nil), t.Policies...)
+	}
+	return &cp
+}
+
+// changed returns true if the token, its expiration or its policies differ
+func (t *VaultTokenInfo) changed(other *VaultTokenInfo) bool {
+	if t == nil || other == nil {
+		return t != other
+	}
+	expireTime, otherExpireTime := t.Expiration(), other.Expiration()
+	if t.ID != other.ID || (expireTime == nil) != (otherExpireTime == nil) ||
+		(expireTime != nil && !expireTime.Equal(*otherExpireTime)) {
+		return true
+	}
+	return !reflect.DeepEqual(t.Policies, other.Policies) ||
+		!reflect.DeepEqual(t.IdentityPolicies, other.IdentityPolicies) ||
+		!reflect.DeepEqual(t.ExternalNamespacePolicies, other.ExternalNamespacePolicies)
+}
+
+// IsExpired returns true if the token expire time is over
+func (t *VaultTokenInfo) IsExpired() bool {
+	expireTime := t.Expiration()
+	return expireTime != nil && !time.Now().Before(*expireTime)
 }
 
 // NewClient returns a new client based on the provided config
+//
+// The client authenticates with, by order of precedence: the config Token,
+// the config TokenFile, the app role credentials and finally ~/.vault-token.
 func NewClient(c *Config) (*Client, error) {
 	var caPool *x509.CertPool
 	// If no config provided, use a new one based on default values and env vars
@@ -101,7 +260,22 @@ func NewClient(c *Config) (*Client, error) {
 	cli.token = new(VaultTokenInfo)
 	cli.token.ID = c.Token
 
-	if cli.token.ID == "" {
+	// fallback to the Vault CLI token file when no other credentials provided
+	tokenFile := c.TokenFile
+	if cli.token.ID == "" && tokenFile == "" &&
+		(cli.appRoleCredentials.RoleID == "" || cli.appRoleCredentials.SecretID == "") {
+		tokenFile = defaultTokenFile()
+	}
+
+	if cli.token.ID == "" && tokenFile != "" {
+		err = cli.setTokenFromFile(tokenFile)
+		if err != nil {
+			// the returned client is not expected to be used, the token is still reloaded on 403
+			cli.StopTokenFileWatch()
+			cli.status = "Authentication Error: " + err.Error()
+			return &cli, err
+		}
+	} else if cli.token.ID == "" {
 		err = cli.setTokenFromAppRole()
 		if err != nil {
 			cli.status = "Authentication Error: " + err.Error()
@@ -129,16 +303,60 @@ func (c *Client) getTokenID() string {
 	return tk
 }
 
-// GetTokenInfo returns the current token information
+// GetTokenInfo returns a snapshot of the current token information.
+//
+// The returned value is a copy: it is not updated on token renewal and can be modified safely.
 func (c *Client) GetTokenInfo() *VaultTokenInfo {
-	vt := new(VaultTokenInfo)
+	var vt *VaultTokenInfo
 	c.withLockContext(func() {
-		vt = c.token
+		vt = c.token.copy()
 	})
 	return vt
 
 }
 
+// TokenInfoChanged subscribes to the client's token changes.
+//
+// A snapshot of the token information is sent on the returned channel each time the token,
+// its expiration (ie after renewal) or its policies change. Only the latest change is kept
+// if the channel is not read. Call the returned function to unsubscribe.
+func (c *Client) TokenInfoChanged() (<-chan *VaultTokenInfo, func()) {
+	ch := make(chan *VaultTokenInfo, 1)
+	c.withLockContext(func() {
+		if c.tokenSubscribers == nil {
+			c.tokenSubscribers = make(map[chan *VaultTokenInfo]struct{})
+		}
+		c.tokenSubscribers[ch] = struct{}{}
+	})
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			c.withLockContext(func() {
+				delete(c.tokenSubscribers, ch)
+			})
+		})
+	}
+}
+
+// notifyTokenChanged sends the token snapshot to the subscribers, must be called with the lock held
+func (c *Client) notifyTokenChanged() {
+	for ch := range c.tokenSubscribers {
+		select {
+		case ch <- c.token.copy():
+		default:
+			// drop the unread snapshot, keep the latest
+			select {
+			case <-ch:
+			default:
+			}
+			select {
+			case ch <- c.token.copy():
+			default:
+			}
+		}
+	}
+}
+
 func (c *Client) setStatus(status string) {
 	c.withLockContext(func() {
 		c.status = status
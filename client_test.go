@@ -1,11 +1,14 @@
 package vaultlib
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestNewClient(t *testing.T) {
@@ -182,3 +185,92 @@ func TestClient_GetStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestVaultTokenInfo_Unmarshal(t *testing.T) {
+	lookupSelf := []byte(`{"accessor":"8609694a","creation_time":1523979354,"creation_ttl":2764800,
+		"display_name":"ldap2-tesla","entity_id":"7d2e3179","expire_time":"2018-05-19T11:35:54.466476215-04:00",
+		"explicit_max_ttl":0,"external_namespace_policies":{"ns1":["ns-policy"]},"id":"cf64a70f",
+		"identity_policies":["dev-group-policy"],"issue_time":"2018-04-17T11:35:54.466476078-04:00",
+		"last_renewal":"2018-04-18T11:35:54.466476078-04:00","last_renewal_time":1524065754,
+		"meta":{"username":"tesla"},"num_uses":0,"orphan":true,"path":"auth/token/create",
+		"period":3600,"policies":["default","testgroup2"],"renewable":true,"ttl":2764790,"type":"service"}`)
+	var got VaultTokenInfo
+	if err := json.Unmarshal(lookupSelf, &got); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	wantExpire, _ := time.Parse(time.RFC3339Nano, "2018-05-19T11:35:54.466476215-04:00")
+	if got.ExpireTime != "2018-05-19T11:35:54.466476215-04:00" || got.Expiration() == nil || !got.Expiration().Equal(wantExpire) {
+		t.Errorf("VaultTokenInfo.Expiration() = %v, want %v", got.Expiration(), wantExpire)
+	}
+	if !reflect.DeepEqual(got.Meta, map[string]interface{}{"username": "tesla"}) ||
+		!reflect.DeepEqual(got.Metadata(), map[string]string{"username": "tesla"}) {
+		t.Errorf("VaultTokenInfo.Metadata() = %v", got.Metadata())
+	}
+	if !reflect.DeepEqual(got.IdentityPolicies, []string{"dev-group-policy"}) ||
+		!reflect.DeepEqual(got.ExternalNamespacePolicies, map[string][]string{"ns1": {"ns-policy"}}) {
+		t.Errorf("VaultTokenInfo identity policies = %v %v", got.IdentityPolicies, got.ExternalNamespacePolicies)
+	}
+	if got.PeriodDuration() != time.Hour || got.CreationTTLDuration() != 768*time.Hour {
+		t.Errorf("VaultTokenInfo durations = %v %v", got.PeriodDuration(), got.CreationTTLDuration())
+	}
+	if got.Created().Unix() != 1523979354 || got.LastRenewal == nil {
+		t.Errorf("VaultTokenInfo times = %v %v", got.Created(), got.LastRenewal)
+	}
+}
+
+func TestVaultTokenInfo_TimeRemaining(t *testing.T) {
+	past := time.Now().Add(-time.Minute).Format(time.RFC3339Nano)
+	future := time.Now().Add(time.Hour).Format(time.RFC3339Nano)
+	tests := []struct {
+		name        string
+		token       *VaultTokenInfo
+		wantExpired bool
+		wantMin     time.Duration
+		wantMax     time.Duration
+	}{
+		{"noExpire", &VaultTokenInfo{}, false, time.Duration(math.MaxInt64), time.Duration(math.MaxInt64)},
+		{"expired", &VaultTokenInfo{ExpireTime: past}, true, 0, 0},
+		{"valid", &VaultTokenInfo{ExpireTime: future}, false, 59 * time.Minute, time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.token.IsExpired(); got != tt.wantExpired {
+				t.Errorf("VaultTokenInfo.IsExpired() = %v, want %v", got, tt.wantExpired)
+			}
+			if got := tt.token.TimeRemaining(); got < tt.wantMin || got > tt.wantMax {
+				t.Errorf("VaultTokenInfo.TimeRemaining() = %v, want between %v and %v", got, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestClient_GetTokenInfoSnapshot(t *testing.T) {
+	c := &Client{token: &VaultTokenInfo{ID: "my-token", Policies: []string{"default"},
+		Meta: map[string]interface{}{"k": "v"}}}
+	got := c.GetTokenInfo()
+	got.Policies[0] = "root"
+	got.Meta.(map[string]interface{})["k"] = "changed"
+	if c.token.Policies[0] != "default" || c.token.Metadata()["k"] != "v" {
+		t.Errorf("Client.GetTokenInfo() returned shared data, client token is now %v", c.token)
+	}
+}
+
+func TestClient_TokenInfoChanged(t *testing.T) {
+	conf := NewConfig()
+	conf.Token = "my-renewable-token"
+	vc, err := NewClient(conf)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	changes, unsubscribe := vc.TokenInfoChanged()
+	defer unsubscribe()
+	before := vc.GetTokenInfo()
+	select {
+	case got := <-changes:
+		if got.Expiration() == nil || !got.Expiration().After(*before.Expiration()) {
+			t.Errorf("Client.TokenInfoChanged() = %v, want expire time after %v", got.ExpireTime, before.ExpireTime)
+		}
+	case <-time.After(15 * time.Second):
+		t.Errorf("Client.TokenInfoChanged() no change received after token renewal")
+	}
+}
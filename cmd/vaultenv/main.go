@@ -0,0 +1,114 @@
+// Command vaultenv runs a command with Vault secrets injected as environment variables.
+//
+// Usage:
+//
+//	vaultenv [-prefix APP_] [-overwrite] -secret <path> [-secret <path>...] [--] <command> [args...]
+//
+// Each key of the secrets becomes an environment variable, upper cased and sanitized
+// (ie "db.password" gives "DB_PASSWORD", "APP_DB_PASSWORD" with prefix "APP_").
+// The signals are forwarded to the command and vaultenv exits with the command exit code.
+//
+// Without command, the variables are printed as shell export statements:
+//
+//	eval "$(vaultenv -secret kv_v2/path/my-secret)"
+//
+// The Vault client is configured from the environment, see vaultlib.NewConfig.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	vault "github.com/mch1307/vaultlib"
+	"github.com/pkg/errors"
+)
+
+// exit codes of vaultenv own failures, following env(1)
+const (
+	exitBadUsage  = 2
+	exitFailure   = 125
+	exitCannotRun = 126
+	exitNotFound  = 127
+)
+
+// pathList is a repeatable string flag
+type pathList []string
+
+func (p *pathList) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *pathList) Set(v string) error {
+	*p = append(*p, v)
+	return nil
+}
+
+func main() {
+	var paths pathList
+	var opts vault.EnvOptions
+	flag.Var(&paths, "secret", "path of a secret to inject, repeatable")
+	flag.StringVar(&opts.Prefix, "prefix", "", "prefix of the environment variable names")
+	flag.BoolVar(&opts.Overwrite, "overwrite", false, "replace the variables already set in the environment")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] -secret <path> [--] [command [args...]]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if len(paths) == 0 {
+		flag.Usage()
+		os.Exit(exitBadUsage)
+	}
+
+	client, err := vault.NewClient(vault.NewConfig())
+	if err != nil {
+		fail(exitFailure, err)
+	}
+
+	args := flag.Args()
+	if len(args) == 0 {
+		env, err := client.SecretsEnv(paths, opts)
+		if err != nil {
+			fail(exitFailure, err)
+		}
+		printExports(env)
+		return
+	}
+
+	code, err := client.ExecWithSecrets(paths, opts, args[0], args[1:]...)
+	if err != nil {
+		cause := errors.Cause(err)
+		if execErr, ok := cause.(*exec.Error); ok {
+			cause = execErr.Err
+		}
+		switch {
+		case cause == exec.ErrNotFound || os.IsNotExist(cause):
+			fail(exitNotFound, err)
+		case os.IsPermission(cause):
+			fail(exitCannotRun, err)
+		default:
+			fail(exitFailure, err)
+		}
+	}
+	os.Exit(code)
+}
+
+// printExports prints the variables as shell export statements, sorted by name
+func printExports(env map[string]string) {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("export %s='%s'\n", name, strings.ReplaceAll(env[name], "'", `'"'"'`))
+	}
+}
+
+func fail(code int, err error) {
+	fmt.Fprintln(os.Stderr, "vaultenv:", err)
+	os.Exit(code)
+}
@@ -0,0 +1,376 @@
+// Command vaultlib reads and writes Vault kv secrets with the vaultlib library.
+//
+// Usage:
+//
+//	vaultlib get [-format table] [-version N] [-field key] <path>
+//	vaultlib put <path> key=value [key=@file...]
+//	vaultlib list [-format table] <path>
+//	vaultlib delete <path>
+//	vaultlib token info [-format table]
+//	vaultlib export [-format dotenv] [-prefix APP_] <path> [path...]
+//	vaultlib export-kv [-versions] [-metadata] [-ndjson | -passphrase-file f | -transit-key k] <path> > export.json
+//	vaultlib import-kv [-format table] [-dry-run] [-overwrite] [-passphrase-file f] <path> [export.json]
+//
+// The output format is json, yaml, dotenv or table. export prints the secrets keys as
+// environment variables (see vaultlib.SecretsEnv), the last path winning.
+//
+// export-kv writes the secrets under path (see vaultlib.Client.ExportKV), import-kv imports
+// them from the file or the standard input (see vaultlib.Client.ImportKV) and prints the report.
+// With a passphrase (-passphrase-file or the VAULTLIB_PASSPHRASE environment variable) or
+// a Transit key (-transit-key), the export is encrypted (see vaultlib.Client.EncryptKVExport).
+// The encrypted exports are always a single JSON document, -ndjson is rejected with encryption.
+// import-kv detects the encrypted exports, the Transit key is read from the export.
+//
+// The Vault client is configured from the environment, see vaultlib.NewConfig.
+// The kv version (1 or 2) is detected from the secret mount.
+//
+// Exit codes:
+//
+//	0  success
+//	1  failure
+//	2  invalid usage
+//	3  secret or path not found (including a mount not visible by the token)
+//	4  permission denied
+//	5  Vault unreachable (connection failure, sealed or unavailable)
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	vault "github.com/mch1307/vaultlib"
+	"github.com/pkg/errors"
+)
+
+// exit codes
+const (
+	exitFailure          = 1
+	exitBadUsage         = 2
+	exitNotFound         = 3
+	exitPermissionDenied = 4
+	exitUnreachable      = 5
+)
+
+// usageError is returned for invalid command lines
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+// notFoundError is returned when a key is missing from a secret
+type notFoundError struct {
+	msg string
+}
+
+func (e *notFoundError) Error() string {
+	return e.msg
+}
+
+const usage = `Usage:
+  vaultlib get [-format table] [-version N] [-field key] <path>
+  vaultlib put <path> key=value [key=@file...]
+  vaultlib list [-format table] <path>
+  vaultlib delete <path>
+  vaultlib token info [-format table]
+  vaultlib export [-format dotenv] [-prefix APP_] <path> [path...]
+  vaultlib export-kv [-versions] [-metadata] [-ndjson | -passphrase-file f | -transit-key k] <path> > export.json
+  vaultlib import-kv [-format table] [-dry-run] [-overwrite] [-passphrase-file f] <path> [export.json]
+
+Formats: json, yaml, dotenv, table
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(exitBadUsage)
+	}
+	if err := run(os.Args[1], os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "vaultlib:", err)
+		if _, ok := err.(*usageError); ok {
+			fmt.Fprint(os.Stderr, usage)
+		}
+		os.Exit(exitCode(err))
+	}
+}
+
+// run runs the command with its arguments
+func run(command string, args []string) error {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	format := flags.String("format", "table", "output format: json, yaml, dotenv or table")
+	version := flags.Int("version", 0, "kv v2 secret version (get)")
+	field := flags.String("field", "", "print only the value of this key (get)")
+	prefix := flags.String("prefix", "", "environment variable names prefix (export)")
+	var exportOpts vault.KVExportOptions
+	var importOpts vault.KVImportOptions
+	flags.BoolVar(&exportOpts.AllVersions, "versions", false, "export all the kv v2 secret versions (export-kv)")
+	flags.BoolVar(&exportOpts.Metadata, "metadata", false, "export the kv v2 custom metadata (export-kv)")
+	ndjson := flags.Bool("ndjson", false, "write NDJSON instead of a JSON document (export-kv)")
+	flags.BoolVar(&importOpts.DryRun, "dry-run", false, "report without writing (import-kv)")
+	flags.BoolVar(&importOpts.Overwrite, "overwrite", false, "replace the existing secrets (import-kv)")
+	encryption := vault.KVEncryption{Passphrase: os.Getenv("VAULTLIB_PASSPHRASE")}
+	passphraseFile := flags.String("passphrase-file", "", "file holding the export passphrase (export-kv, import-kv)")
+	flags.StringVar(&encryption.TransitKey, "transit-key", "", "Transit key encrypting the export (export-kv)")
+	flags.StringVar(&encryption.TransitMount, "transit-mount", "transit", "Transit mount point (export-kv)")
+	if command == "export" {
+		*format = "dotenv"
+	}
+	if command == "token" {
+		if len(args) == 0 || args[0] != "info" {
+			return &usageError{"unknown token command, expected token info"}
+		}
+		args = args[1:]
+	}
+	if err := flags.Parse(args); err != nil {
+		return &usageError{err.Error()}
+	}
+	if !validFormat(*format) {
+		return &usageError{"unknown format " + *format}
+	}
+	args = flags.Args()
+	if *passphraseFile != "" {
+		content, err := ioutil.ReadFile(*passphraseFile)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		encryption.Passphrase = strings.TrimRight(string(content), "\r\n")
+	}
+
+	switch command {
+	case "get", "list", "delete", "export-kv":
+		if len(args) != 1 {
+			return &usageError{command + " expects a single path"}
+		}
+	case "put", "export":
+		if len(args) < 1 {
+			return &usageError{command + " expects a path"}
+		}
+	case "import-kv":
+		if len(args) < 1 || len(args) > 2 {
+			return &usageError{"import-kv expects a path and an optional file"}
+		}
+	case "token":
+		if len(args) != 0 {
+			return &usageError{"token info expects no argument"}
+		}
+	default:
+		return &usageError{"unknown command " + command}
+	}
+	if command == "export-kv" && *ndjson && (encryption.Passphrase != "" || encryption.TransitKey != "") {
+		return &usageError{"-ndjson is not supported for the encrypted exports"}
+	}
+
+	client, err := vault.NewClient(vault.NewConfig())
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "get":
+		secret, err := client.GetSecretVersion(args[0], *version)
+		if err != nil {
+			return err
+		}
+		data, err := secretData(secret)
+		if err != nil {
+			return err
+		}
+		if *field != "" {
+			value, ok := data[*field]
+			if !ok {
+				return &notFoundError{"key " + *field + " not found in secret " + args[0]}
+			}
+			fmt.Println(stringValue(value))
+			return nil
+		}
+		return writeOutput(os.Stdout, *format, data)
+	case "put":
+		data, err := parsePairs(args[1:])
+		if err != nil {
+			return err
+		}
+		return client.PutSecret(args[0], data)
+	case "list":
+		keys, err := client.ListSecrets(args[0])
+		if err != nil {
+			return err
+		}
+		list := make([]interface{}, len(keys))
+		for i, key := range keys {
+			list[i] = key
+		}
+		return writeOutput(os.Stdout, *format, list)
+	case "delete":
+		return client.DeleteSecret(args[0])
+	case "token":
+		info, err := toGeneric(client.GetTokenInfo())
+		if err != nil {
+			return err
+		}
+		// never print the token itself
+		delete(info.(map[string]interface{}), "id")
+		return writeOutput(os.Stdout, *format, info)
+	case "export":
+		env, err := client.SecretsEnv(args, vault.EnvOptions{Prefix: *prefix})
+		if err != nil {
+			return err
+		}
+		data := make(map[string]interface{}, len(env))
+		for name, value := range env {
+			data[name] = value
+		}
+		return writeOutput(os.Stdout, *format, data)
+	case "export-kv":
+		export, err := client.ExportKV(args[0], exportOpts)
+		if err != nil {
+			return err
+		}
+		if encryption.Passphrase != "" || encryption.TransitKey != "" {
+			return client.EncryptKVExport(os.Stdout, export, encryption)
+		}
+		return export.Write(os.Stdout, *ndjson)
+	case "import-kv":
+		export, err := readExport(client, args[1:], encryption)
+		if err != nil {
+			return err
+		}
+		report, err := client.ImportKV(args[0], export, importOpts)
+		if report != nil {
+			if outErr := writeReport(os.Stdout, *format, report); err == nil {
+				err = outErr
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// readExport reads the export from the file or the standard input, decrypting it if encrypted
+func readExport(client *vault.Client, file []string, encryption vault.KVEncryption) (*vault.KVExport, error) {
+	var content []byte
+	var err error
+	if len(file) > 0 {
+		content, err = ioutil.ReadFile(file[0])
+	} else {
+		content, err = ioutil.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var header struct {
+		Format string `json:"format"`
+	}
+	if err = json.NewDecoder(bytes.NewReader(content)).Decode(&header); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if header.Format == vault.KVBundleFormat {
+		return client.DecryptKVExport(bytes.NewReader(content), encryption)
+	}
+	return vault.ReadKVExport(bytes.NewReader(content))
+}
+
+// writeReport writes the import report, the paths by status
+func writeReport(w io.Writer, format string, report *vault.KVImportReport) error {
+	if format == "dotenv" {
+		return &usageError{"dotenv format is not supported for import-kv"}
+	}
+	if format == "table" {
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "STATUS\tPATH")
+		for _, status := range []struct {
+			name  string
+			paths []string
+		}{{"created", report.Created}, {"updated", report.Updated}, {"skipped", report.Skipped}} {
+			for _, path := range status.paths {
+				fmt.Fprintf(tw, "%s\t%s\n", status.name, path)
+			}
+		}
+		return errors.WithStack(tw.Flush())
+	}
+	paths := func(p []string) []string {
+		if p == nil {
+			return []string{}
+		}
+		return p
+	}
+	data, err := toGeneric(map[string][]string{"created": paths(report.Created), "updated": paths(report.Updated), "skipped": paths(report.Skipped)})
+	if err != nil {
+		return err
+	}
+	return writeOutput(w, format, data)
+}
+
+// parsePairs parses the key=value arguments, a value starting with @ is read from the file
+func parsePairs(pairs []string) (map[string]interface{}, error) {
+	if len(pairs) == 0 {
+		return nil, &usageError{"put expects key=value pairs"}
+	}
+	data := make(map[string]interface{}, len(pairs))
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, &usageError{"invalid key=value pair " + pair}
+		}
+		if strings.HasPrefix(kv[1], "@") {
+			content, err := ioutil.ReadFile(kv[1][1:])
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			kv[1] = string(content)
+		}
+		data[kv[0]] = kv[1]
+	}
+	return data, nil
+}
+
+// secretData returns the secret values, JSON secrets keep their structure
+func secretData(secret vault.Secret) (map[string]interface{}, error) {
+	var source interface{} = secret.KV
+	if secret.JSONSecret != nil {
+		source = secret.JSONSecret
+	}
+	data, err := toGeneric(source)
+	if err != nil {
+		return nil, err
+	}
+	values, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("Unexpected secret format")
+	}
+	return values, nil
+}
+
+// exitCode returns the exit code matching the error
+func exitCode(err error) int {
+	switch cause := errors.Cause(err).(type) {
+	case *usageError:
+		return exitBadUsage
+	case *notFoundError, *vault.KVMountNotFoundError:
+		return exitNotFound
+	case *vault.ResponseError:
+		switch {
+		case cause.StatusCode == http.StatusNotFound:
+			return exitNotFound
+		case cause.StatusCode == http.StatusForbidden || cause.StatusCode == http.StatusUnauthorized:
+			return exitPermissionDenied
+		case cause.StatusCode >= 500:
+			return exitUnreachable
+		}
+	case *url.Error:
+		return exitUnreachable
+	}
+	return exitFailure
+}
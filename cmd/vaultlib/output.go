@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	vault "github.com/mch1307/vaultlib"
+	"github.com/pkg/errors"
+)
+
+// yamlPlain matches the identifier-like strings written unquoted in YAML, the others are quoted:
+// strings starting with a digit may be read as numbers, dates or times (ie 0x1F, 2001-12-14)
+var yamlPlain = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_./@-]*$`)
+
+// yamlReserved are the plain YAML scalars which are not read as strings
+var yamlReserved = map[string]bool{
+	"true": true, "false": true, "yes": true, "no": true, "on": true, "off": true,
+	"y": true, "n": true, "null": true, "~": true,
+}
+
+// dotenvEscaper escapes the dotenv double quoted values
+var dotenvEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`", "\n", `\n`, "\r", `\r`)
+
+func validFormat(format string) bool {
+	switch format {
+	case "json", "yaml", "dotenv", "table":
+		return true
+	}
+	return false
+}
+
+// toGeneric converts v to its JSON representation: maps, slices, strings, json.Number, bools and nil
+func toGeneric(v interface{}) (interface{}, error) {
+	var raw []byte
+	var err error
+	if msg, ok := v.(json.RawMessage); ok {
+		raw = msg
+	} else if raw, err = json.Marshal(v); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var generic interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err = dec.Decode(&generic); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return generic, nil
+}
+
+// writeOutput writes the generic value (see toGeneric) in the format
+func writeOutput(w io.Writer, format string, v interface{}) error {
+	var buf bytes.Buffer
+	switch format {
+	case "json":
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			return errors.WithStack(err)
+		}
+	case "yaml":
+		writeYAML(&buf, v, "")
+	case "dotenv":
+		values, ok := v.(map[string]interface{})
+		if !ok {
+			return &usageError{"dotenv format is only supported for key/value outputs"}
+		}
+		for _, k := range sortedKeys(values) {
+			fmt.Fprintf(&buf, "%s=\"%s\"\n", vault.EnvName("", k), dotenvEscaper.Replace(stringValue(values[k])))
+		}
+	case "table":
+		tw := tabwriter.NewWriter(&buf, 0, 8, 2, ' ', 0)
+		switch t := v.(type) {
+		case map[string]interface{}:
+			fmt.Fprintln(tw, "KEY\tVALUE")
+			for _, k := range sortedKeys(t) {
+				fmt.Fprintf(tw, "%s\t%s\n", k, stringValue(t[k]))
+			}
+		case []interface{}:
+			fmt.Fprintln(tw, "KEYS")
+			for _, e := range t {
+				fmt.Fprintln(tw, stringValue(e))
+			}
+		default:
+			fmt.Fprintln(tw, stringValue(t))
+		}
+		_ = tw.Flush()
+	}
+	_, err := w.Write(buf.Bytes())
+	return errors.WithStack(err)
+}
+
+// stringValue returns strings as is, the other values JSON encoded
+func stringValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return jsonString(v)
+}
+
+func jsonString(v interface{}) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// writeYAML writes the generic value as a YAML block, nested blocks are indented by 2 spaces
+func writeYAML(buf *bytes.Buffer, v interface{}, indent string) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if len(t) == 0 {
+			buf.WriteString(indent + "{}\n")
+			return
+		}
+		for _, k := range sortedKeys(t) {
+			buf.WriteString(indent + yamlScalar(k) + ":")
+			writeYAMLChild(buf, t[k], indent)
+		}
+	case []interface{}:
+		if len(t) == 0 {
+			buf.WriteString(indent + "[]\n")
+			return
+		}
+		for _, e := range t {
+			buf.WriteString(indent + "-")
+			writeYAMLChild(buf, e, indent)
+		}
+	default:
+		buf.WriteString(indent + yamlScalar(t) + "\n")
+	}
+}
+
+// writeYAMLChild writes a map or slice element after its key or dash
+func writeYAMLChild(buf *bytes.Buffer, v interface{}, indent string) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if len(t) > 0 {
+			buf.WriteString("\n")
+			writeYAML(buf, t, indent+"  ")
+			return
+		}
+		buf.WriteString(" {}\n")
+	case []interface{}:
+		if len(t) > 0 {
+			buf.WriteString("\n")
+			writeYAML(buf, t, indent+"  ")
+			return
+		}
+		buf.WriteString(" []\n")
+	default:
+		buf.WriteString(" " + yamlScalar(t) + "\n")
+	}
+}
+
+// yamlScalar returns the YAML representation of a scalar, strings are double quoted
+// (JSON encoded) unless they can not be mistaken for another type
+func yamlScalar(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(t)
+	case json.Number:
+		return t.String()
+	case string:
+		if _, err := strconv.ParseFloat(t, 64); err != nil && yamlPlain.MatchString(t) && !yamlReserved[strings.ToLower(t)] {
+			return t
+		}
+	}
+	return jsonString(v)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	vault "github.com/mch1307/vaultlib"
+	"github.com/pkg/errors"
+)
+
+func Test_writeOutput(t *testing.T) {
+	data, err := toGeneric(json.RawMessage(`{"user":"app","pass":"a\"b$c\nd","port":5432,"tls":true,"opts":{"ssl":"on","hosts":["db1","db2"],"empty":[]},"none":null,"db.name":"my db"}`))
+	if err != nil {
+		t.Fatalf("toGeneric() error = %v", err)
+	}
+
+	tests := []struct {
+		format string
+		value  interface{}
+		want   string
+	}{
+		{"json", []interface{}{"a", "b/"}, "[\n  \"a\",\n  \"b/\"\n]\n"},
+		{"yaml", data, `db.name: "my db"
+none: null
+opts:
+  empty: []
+  hosts:
+    - db1
+    - db2
+  ssl: "on"
+pass: "a\"b$c\nd"
+port: 5432
+tls: true
+user: app
+`},
+		{"yaml", []interface{}{"a", "12", "-x"}, "- a\n- \"12\"\n- \"-x\"\n"},
+		{"yaml", []interface{}{"2001-12-14", "0x1F", "1:20", ".inf", "12:30:00", "kv/app_1"},
+			"- \"2001-12-14\"\n- \"0x1F\"\n- \"1:20\"\n- \".inf\"\n- \"12:30:00\"\n- kv/app_1\n"},
+		{"dotenv", data, `DB_NAME="my db"
+NONE="null"
+OPTS="{\"empty\":[],\"hosts\":[\"db1\",\"db2\"],\"ssl\":\"on\"}"
+PASS="a\"b\$c\nd"
+PORT="5432"
+TLS="true"
+USER="app"
+`},
+		{"table", map[string]interface{}{"user": "app", "password": "s3cr3t"}, "KEY       VALUE\npassword  s3cr3t\nuser      app\n"},
+		{"table", []interface{}{"a", "b/"}, "KEYS\na\nb/\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := writeOutput(&buf, tt.format, tt.value); err != nil || buf.String() != tt.want {
+			t.Errorf("writeOutput(%v) = %q, %v, want %q", tt.format, buf.String(), err, tt.want)
+		}
+	}
+	if err := writeOutput(&bytes.Buffer{}, "dotenv", []interface{}{"a"}); err == nil {
+		t.Errorf("writeOutput() expected error for a dotenv list")
+	}
+}
+
+func Test_writeReport(t *testing.T) {
+	report := &vault.KVImportReport{Created: []string{"kv/a"}, Skipped: []string{"kv/b", "kv/c"}}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"table", "STATUS   PATH\ncreated  kv/a\nskipped  kv/b\nskipped  kv/c\n"},
+		{"yaml", "created:\n  - kv/a\nskipped:\n  - kv/b\n  - kv/c\nupdated: []\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := writeReport(&buf, tt.format, report); err != nil || buf.String() != tt.want {
+			t.Errorf("writeReport(%v) = %q, %v, want %q", tt.format, buf.String(), err, tt.want)
+		}
+	}
+}
+
+func Test_exitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{errors.New("failure"), exitFailure},
+		{&usageError{"bad"}, exitBadUsage},
+		{errors.Wrap(errors.WithStack(&vault.ResponseError{StatusCode: 404}), "get"), exitNotFound},
+		{&notFoundError{"key"}, exitNotFound},
+		{errors.Wrap(errors.WithStack(&vault.KVMountNotFoundError{Path: "unknown/path"}), "get"), exitNotFound},
+		{errors.WithStack(&vault.ResponseError{StatusCode: 403}), exitPermissionDenied},
+		{errors.WithStack(&vault.ResponseError{StatusCode: 503}), exitUnreachable},
+		{errors.WithStack(&vault.ResponseError{StatusCode: 400}), exitFailure},
+		{errors.Wrap(&url.Error{Op: "Get", URL: "http://localhost:8200", Err: errors.New("refused")}, "get"), exitUnreachable},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("exitCode(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func Test_run_usage(t *testing.T) {
+	tests := [][]string{
+		{"export-kv", "-ndjson", "-transit-key", "backup", "kv_v2/app"},
+		{"export-kv", "kv_v2/app", "kv_v2/other"},
+		{"import-kv"},
+		{"unknown", "kv_v2/app"},
+	}
+	for _, args := range tests {
+		if err := run(args[0], args[1:]); exitCode(err) != exitBadUsage {
+			t.Errorf("run(%v) error = %v, want a usage error", args, err)
+		}
+	}
+}
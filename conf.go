@@ -22,6 +22,7 @@ type Config struct {
 	InsecureSSL        bool
 	AppRoleCredentials *AppRoleCredentials
 	Token              string
+	TokenFile          string
 	Namespace          string
 }
 
@@ -33,6 +34,7 @@ type Config struct {
 //	VAULT_SECRETID        Vault app role secret id
 //	VAULT_MOUNTPOINT      Vault app role mountpoint (default "approle")
 //	VAULT_TOKEN           Vault Token (in case approle is not used)
+//	VAULT_TOKEN_FILE      Path to a file containing the Vault Token (ie Vault Agent sink)
 //	VAULT_CACERT          Path to CA pem file
 //	VAULT_SKIP_VERIFY     Do not check SSL
 //	VAULT_CLIENT_TIMEOUT  Client timeout
@@ -56,6 +58,10 @@ func NewConfig() *Config {
 		cfg.Token = v
 	}
 
+	if v := os.Getenv("VAULT_TOKEN_FILE"); v != "" {
+		cfg.TokenFile = v
+	}
+
 	if v := os.Getenv("VAULT_ROLEID"); v != "" {
 		appRoleCredentials.RoleID = v
 	}
@@ -0,0 +1,108 @@
+package vaultlib
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// cubbyholeMount is the cubbyhole secrets engine mount, always mounted and scoped to the token
+const cubbyholeMount = "cubbyhole/"
+
+// Cubbyhole holds the cubbyhole secrets engine client.
+//
+// Each token has its own cubbyhole, destroyed with the token.
+type Cubbyhole struct {
+	client *Client
+	token  string
+}
+
+// Cubbyhole returns a client for the cubbyhole of the client's token
+func (c *Client) Cubbyhole() *Cubbyhole {
+	return &Cubbyhole{client: c}
+}
+
+// CubbyholeWithToken returns a client for the cubbyhole of token (ie a wrapping token),
+// the client's token is left unchanged
+func (c *Client) CubbyholeWithToken(token string) *Cubbyhole {
+	return &Cubbyhole{client: c, token: token}
+}
+
+// Read returns the secret at path (ie "my-secret" or "cubbyhole/my-secret")
+func (cb *Cubbyhole) Read(path string) (secret Secret, err error) {
+	var data json.RawMessage
+	if err = cb.do("GET", path, nil, &data); err != nil {
+		return secret, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if err = parseSecretData("1", data, &secret); err != nil {
+		return secret, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return secret, nil
+}
+
+// Write stores data at path, replacing the existing secret
+func (cb *Cubbyhole) Write(path string, data map[string]interface{}) error {
+	if err := cb.do("POST", path, data, nil); err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	cb.invalidate(path)
+	return nil
+}
+
+// List returns the keys under path, folders end with "/"
+func (cb *Cubbyhole) List(path string) ([]string, error) {
+	var list struct {
+		Keys []string `json:"keys"`
+	}
+	if err := cb.do("LIST", path, nil, &list); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return list.Keys, nil
+}
+
+// Delete deletes the secret at path
+func (cb *Cubbyhole) Delete(path string) error {
+	if err := cb.do("DELETE", path, nil, nil); err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	cb.invalidate(path)
+	return nil
+}
+
+// invalidate removes the secret from the client's secret cache, only the client's token cubbyhole is cached
+func (cb *Cubbyhole) invalidate(path string) {
+	if cb.token == "" {
+		cb.client.invalidateSecret(cubbyholeMount + strings.TrimPrefix(path, cubbyholeMount))
+	}
+}
+
+// do sends the request to the cubbyhole path with the cubbyhole token,
+// parses the response data into out (if not nil)
+func (cb *Cubbyhole) do(method, path string, payload, out interface{}) error {
+	url := cb.client.address.String() + "/v1/" + cubbyholeMount + strings.TrimPrefix(path, cubbyholeMount)
+
+	req, err := cb.client.newRequest(method, url)
+	if err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if cb.token != "" {
+		req.Req.Header.Set("X-Vault-Token", cb.token)
+	}
+	if payload != nil {
+		if err = req.setJSONBody(payload); err != nil {
+			return errors.Wrap(errors.WithStack(err), errInfo())
+		}
+	}
+
+	rsp, err := req.execute()
+	if err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if out != nil && len(rsp.Data) > 0 {
+		if err = json.Unmarshal([]byte(rsp.Data), out); err != nil {
+			return errors.Wrap(errors.WithStack(err), errInfo())
+		}
+	}
+	return nil
+}
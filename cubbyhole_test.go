@@ -0,0 +1,55 @@
+package vaultlib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCubbyhole(t *testing.T) {
+	conf := NewConfig()
+	conf.Token = "my-dev-root-vault-token"
+	vc, _ := NewClient(conf)
+	cubbyhole := vc.Cubbyhole()
+
+	if err := cubbyhole.Write("bootstrap/my-secret", map[string]interface{}{"my-key": "my-value"}); err != nil {
+		t.Fatalf("Cubbyhole.Write() error = %v", err)
+	}
+	got, err := cubbyhole.Read("cubbyhole/bootstrap/my-secret")
+	if err != nil || !reflect.DeepEqual(got.KV, map[string]string{"my-key": "my-value"}) {
+		t.Errorf("Cubbyhole.Read() = %v, %v", got.KV, err)
+	}
+	secret, err := vc.GetSecret("cubbyhole/bootstrap/my-secret")
+	if err != nil || !reflect.DeepEqual(secret.KV, got.KV) {
+		t.Errorf("Client.GetSecret() = %v, %v", secret.KV, err)
+	}
+	keys, err := cubbyhole.List("bootstrap")
+	if err != nil || !reflect.DeepEqual(keys, []string{"my-secret"}) {
+		t.Errorf("Cubbyhole.List() = %v, %v", keys, err)
+	}
+
+	// each token has its own cubbyhole
+	other, err := vc.CreateToken(&TokenCreateRequest{Policies: []string{"default"}})
+	if err != nil {
+		t.Fatalf("Client.CreateToken() error = %v", err)
+	}
+	otherCubbyhole := vc.CubbyholeWithToken(other.ClientToken)
+	if _, err = otherCubbyhole.Read("bootstrap/my-secret"); err == nil {
+		t.Errorf("Cubbyhole.Read() expected error reading another token cubbyhole")
+	}
+	if err = otherCubbyhole.Write("my-secret", map[string]interface{}{"other-key": "other-value"}); err != nil {
+		t.Errorf("Cubbyhole.Write() error = %v", err)
+	}
+	if got, err = otherCubbyhole.Read("my-secret"); err != nil || got.KV["other-key"] != "other-value" {
+		t.Errorf("Cubbyhole.Read() = %v, %v", got.KV, err)
+	}
+	if vc.GetTokenInfo().ID != "my-dev-root-vault-token" {
+		t.Errorf("CubbyholeWithToken() changed the client token")
+	}
+
+	if err = cubbyhole.Delete("bootstrap/my-secret"); err != nil {
+		t.Errorf("Cubbyhole.Delete() error = %v", err)
+	}
+	if _, err = cubbyhole.Read("bootstrap/my-secret"); err == nil {
+		t.Errorf("Cubbyhole.Read() expected error after delete")
+	}
+}
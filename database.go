@@ -0,0 +1,45 @@
+package vaultlib
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// DatabaseCredentials holds the dynamic credentials generated by the database secrets engine
+type DatabaseCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Lease    Lease  `json:"-"`
+}
+
+// GetDynamicCredentials returns new credentials for the role of the database secrets engine
+// mounted at "database", along with their lease.
+//
+// Use WatchLease to keep the credentials valid.
+func (c *Client) GetDynamicCredentials(role string) (*DatabaseCredentials, error) {
+	return c.GetDynamicCredentialsFromMount("database", role)
+}
+
+// GetDynamicCredentialsFromMount returns new credentials for the role of the database
+// secrets engine mounted at mountPoint, along with their lease.
+func (c *Client) GetDynamicCredentialsFromMount(mountPoint, role string) (*DatabaseCredentials, error) {
+	var creds DatabaseCredentials
+	url := c.address.String() + "/v1/" + mountPoint + "/creds/" + role
+
+	req, err := c.newRequest("GET", url)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	rsp, err := req.execute()
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	if err = json.Unmarshal([]byte(rsp.Data), &creds); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	creds.Lease = leaseFromResponse(rsp)
+	return &creds, nil
+}
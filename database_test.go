@@ -0,0 +1,49 @@
+package vaultlib
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClient_GetDynamicCredentials(t *testing.T) {
+	srv, vc := newTestVault(t)
+	creds := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"lease_id":"database/creds/my-role/abcd","renewable":true,"lease_duration":3600,
+			"data":{"username":"v-token-my-role-x","password":"A1a-secret"}}`))
+	}
+	srv.HandleFunc("database/creds/my-role", creds)
+	srv.HandleFunc("my-db/creds/my-role", creds)
+
+	tests := []struct {
+		name       string
+		mountPoint string
+		role       string
+		want       *DatabaseCredentials
+		wantErr    bool
+	}{
+		{"default", "", "my-role", &DatabaseCredentials{"v-token-my-role-x", "A1a-secret",
+			Lease{"database/creds/my-role/abcd", time.Hour, true}}, false},
+		{"mountPoint", "my-db", "my-role", &DatabaseCredentials{"v-token-my-role-x", "A1a-secret",
+			Lease{"database/creds/my-role/abcd", time.Hour, true}}, false},
+		{"unknownRole", "", "unknown", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *DatabaseCredentials
+			var err error
+			if tt.mountPoint == "" {
+				got, err = vc.GetDynamicCredentials(tt.role)
+			} else {
+				got, err = vc.GetDynamicCredentialsFromMount(tt.mountPoint, tt.role)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Client.GetDynamicCredentials() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && *got != *tt.want {
+				t.Errorf("Client.GetDynamicCredentials() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
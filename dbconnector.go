@@ -0,0 +1,161 @@
+package vaultlib
+
+import (
+	"context"
+	"database/sql/driver"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// dbConnectorRetryInterval is the delay between two credentials fetch attempts after a failure
+var dbConnectorRetryInterval = 5 * time.Second
+
+// DBConnectorConfig holds the database connector config.
+//
+// MountPoint is the database secrets engine mount point (default "database").
+//
+// Role is the database secrets engine role to get credentials from.
+//
+// Driver is the underlying database driver (ie &pq.Driver{}).
+//
+// DSN returns the driver data source name for the given credentials.
+//
+// RenewFraction is the fraction of the lease duration after which the lease is renewed (default 2/3).
+type DBConnectorConfig struct {
+	MountPoint    string
+	Role          string
+	Driver        driver.Driver
+	DSN           func(username, password string) string
+	RenewFraction float64
+}
+
+// DBConnector is a database/sql/driver.Connector opening connections with
+// Vault dynamic database credentials.
+//
+// The credentials lease is renewed in the background. New credentials are fetched
+// when the lease can no longer be renewed: new connections use them while the
+// existing ones keep the previous credentials until they are closed.
+// Set sql.DB SetConnMaxLifetime below the lease max TTL so that connections are recycled.
+type DBConnector struct {
+	sync.RWMutex
+	client    *Client
+	config    DBConnectorConfig
+	creds     *DatabaseCredentials
+	connector driver.Connector
+	connDSN   string
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+var _ driver.Connector = (*DBConnector)(nil)
+
+// NewDBConnector fetches credentials for the role and returns a connector to use with sql.OpenDB
+func (c *Client) NewDBConnector(config DBConnectorConfig) (*DBConnector, error) {
+	if config.Driver == nil || config.DSN == nil || config.Role == "" {
+		return nil, errors.New("Driver, DSN and Role must be specified")
+	}
+	if config.MountPoint == "" {
+		config.MountPoint = "database"
+	}
+
+	creds, err := c.GetDynamicCredentialsFromMount(config.MountPoint, config.Role)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	dc := &DBConnector{
+		client: c,
+		config: config,
+		creds:  creds,
+		stop:   make(chan struct{}),
+	}
+	go dc.refreshCredentials()
+	return dc, nil
+}
+
+// Connect opens a new connection with the current credentials
+func (dc *DBConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	dc.RLock()
+	dsn := dc.config.DSN(dc.creds.Username, dc.creds.Password)
+	connector := dc.connector
+	if dc.connDSN != dsn {
+		connector = nil
+	}
+	dc.RUnlock()
+
+	if connector != nil {
+		return connector.Connect(ctx)
+	}
+
+	driverCtx, ok := dc.config.Driver.(driver.DriverContext)
+	if !ok {
+		return dc.config.Driver.Open(dsn)
+	}
+	// the driver connector is kept until the credentials change
+	connector, err := driverCtx.OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	dc.Lock()
+	dc.connector = connector
+	dc.connDSN = dsn
+	dc.Unlock()
+	return connector.Connect(ctx)
+}
+
+// Driver returns the underlying driver
+func (dc *DBConnector) Driver() driver.Driver {
+	return dc.config.Driver
+}
+
+// Credentials returns the credentials used for new connections
+func (dc *DBConnector) Credentials() DatabaseCredentials {
+	dc.RLock()
+	defer dc.RUnlock()
+	return *dc.creds
+}
+
+// Close stops the credentials renewal. Called by sql.DB Close.
+//
+// The current lease is not revoked.
+func (dc *DBConnector) Close() error {
+	dc.stopOnce.Do(func() {
+		close(dc.stop)
+	})
+	return nil
+}
+
+// refreshCredentials renews the credentials lease and fetches new credentials when needed,
+// launched at connector creation time as a go routine
+func (dc *DBConnector) refreshCredentials() {
+	watcher := dc.client.WatchLease(dc.Credentials().Lease, dc.config.RenewFraction)
+	for {
+		select {
+		case <-dc.stop:
+			watcher.Stop()
+			return
+		case err := <-watcher.Done():
+			if err != nil {
+				dc.client.setStatus("Error renewing database credentials lease " + err.Error())
+			}
+		}
+
+		creds, err := dc.client.GetDynamicCredentialsFromMount(dc.config.MountPoint, dc.config.Role)
+		for err != nil {
+			dc.client.setStatus("Error fetching database credentials " + err.Error())
+			select {
+			case <-dc.stop:
+				return
+			case <-time.After(dbConnectorRetryInterval):
+			}
+			creds, err = dc.client.GetDynamicCredentialsFromMount(dc.config.MountPoint, dc.config.Role)
+		}
+
+		dc.Lock()
+		dc.creds = creds
+		dc.Unlock()
+		watcher = dc.client.WatchLease(creds.Lease, dc.config.RenewFraction)
+	}
+}
@@ -0,0 +1,183 @@
+package vaultlib
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// forwardedSignals are the signals forwarded to the child process by ExecWithSecrets
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT}
+
+// EnvOptions holds the secrets to environment variables mapping options.
+//
+// Prefix is prepended to the variable names (ie "APP_").
+//
+// Overwrite replaces the variables already set in the environment, they are kept otherwise.
+type EnvOptions struct {
+	Prefix    string
+	Overwrite bool
+}
+
+// EnvName returns the environment variable name of the secret key: prefix and key
+// upper cased, characters other than letters, digits and underscore replaced by an underscore,
+// prefixed with an underscore if starting with a digit (ie "app-", "db.password" gives "APP_DB_PASSWORD").
+func EnvName(prefix, key string) string {
+	name := []byte(strings.ToUpper(prefix + key))
+	for i, ch := range name {
+		if (ch < 'A' || ch > 'Z') && (ch < '0' || ch > '9') && ch != '_' {
+			name[i] = '_'
+		}
+	}
+	if len(name) > 0 && name[0] >= '0' && name[0] <= '9' {
+		return "_" + string(name)
+	}
+	return string(name)
+}
+
+// SecretsEnv reads the secrets with GetSecret and returns their keys mapped to
+// environment variable names (see EnvName).
+//
+// The JSON secrets top level values which are not strings are JSON encoded.
+// If the same variable name is produced by several secrets, the last path wins.
+func (c *Client) SecretsEnv(paths []string, opts EnvOptions) (map[string]string, error) {
+	env := make(map[string]string)
+	for _, path := range paths {
+		secret, err := c.GetSecret(path)
+		if err != nil {
+			return nil, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		fields, err := secretFields(secret)
+		if err != nil {
+			return nil, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		for k, v := range fields {
+			env[EnvName(opts.Prefix, k)] = v
+		}
+	}
+	return env, nil
+}
+
+// ExportSecretsEnv sets the secrets environment variables (see SecretsEnv) in the current process
+func (c *Client) ExportSecretsEnv(paths []string, opts EnvOptions) error {
+	env, err := c.SecretsEnv(paths, opts)
+	if err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	for name, value := range env {
+		if _, set := os.LookupEnv(name); set && !opts.Overwrite {
+			continue
+		}
+		if err = os.Setenv(name, value); err != nil {
+			return errors.Wrap(errors.WithStack(err), errInfo())
+		}
+	}
+	return nil
+}
+
+// ExecWithSecrets runs the command with the current environment augmented with the secrets
+// environment variables (see SecretsEnv), waits for it and returns its exit code.
+//
+// The command shares the standard input and outputs of the current process.
+// Interrupt, SIGTERM, SIGHUP and SIGQUIT are forwarded to the command while it runs.
+// If the command is killed by a signal, the exit code is 128 + the signal number.
+func (c *Client) ExecWithSecrets(paths []string, opts EnvOptions, name string, args ...string) (int, error) {
+	env, err := c.SecretsEnv(paths, opts)
+	if err != nil {
+		return -1, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	cmd := exec.Command(name, args...)
+	cmd.Env = mergeEnv(os.Environ(), env, opts.Overwrite)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err = cmd.Start(); err != nil {
+		return -1, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				_ = cmd.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err = cmd.Wait()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal()), nil
+		}
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return -1, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return 0, nil
+}
+
+// mergeEnv returns environ ("NAME=value" list) with the variables of env added,
+// the variables already set in environ are replaced if overwrite
+func mergeEnv(environ []string, env map[string]string, overwrite bool) []string {
+	merged := make([]string, 0, len(environ)+len(env))
+	set := make(map[string]bool, len(environ))
+	for _, kv := range environ {
+		name := strings.SplitN(kv, "=", 2)[0]
+		if _, ok := env[name]; ok && overwrite {
+			continue
+		}
+		set[name] = true
+		merged = append(merged, kv)
+	}
+
+	names := make([]string, 0, len(env))
+	for name := range env {
+		if !set[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		merged = append(merged, name+"="+env[name])
+	}
+	return merged
+}
+
+// secretFields returns the secret top level values as strings,
+// JSON secrets values which are not strings are JSON encoded
+func secretFields(secret Secret) (map[string]string, error) {
+	fields := make(map[string]string, len(secret.KV))
+	for k, v := range secret.KV {
+		fields[k] = v
+	}
+	if secret.JSONSecret == nil {
+		return fields, nil
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(secret.JSONSecret, &raw); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	for k, v := range raw {
+		var value string
+		if json.Unmarshal(v, &value) == nil {
+			fields[k] = value
+		} else {
+			fields[k] = string(v)
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,102 @@
+package vaultlib
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestEnvName(t *testing.T) {
+	tests := []struct {
+		prefix string
+		key    string
+		want   string
+	}{
+		{"", "password", "PASSWORD"},
+		{"app-", "db.password", "APP_DB_PASSWORD"},
+		{"", "my key/1", "MY_KEY_1"},
+		{"", "1st", "_1ST"},
+		{"APP_", "Already_OK", "APP_ALREADY_OK"},
+		{"", "clé", "CL__"},
+	}
+	for _, tt := range tests {
+		if got := EnvName(tt.prefix, tt.key); got != tt.want {
+			t.Errorf("EnvName(%q, %q) = %v, want %v", tt.prefix, tt.key, got, tt.want)
+		}
+	}
+}
+
+func Test_mergeEnv(t *testing.T) {
+	environ := []string{"PATH=/bin", "DB_USER=env", "EMPTY="}
+	env := map[string]string{"DB_USER": "vault", "DB_PASSWORD": "pass", "EMPTY": "vault"}
+
+	if got, want := mergeEnv(environ, env, false), []string{"PATH=/bin", "DB_USER=env", "EMPTY=", "DB_PASSWORD=pass"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeEnv() = %v, want %v", got, want)
+	}
+	if got, want := mergeEnv(environ, env, true), []string{"PATH=/bin", "DB_PASSWORD=pass", "DB_USER=vault", "EMPTY=vault"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeEnv() overwrite = %v, want %v", got, want)
+	}
+}
+
+// newTestEnvClient returns a client of a fake Vault holding a kv v1 and a kv v2 secret
+func newTestEnvClient(t *testing.T) *Client {
+	srv, vc := newTestVault(t)
+	srv.PutSecret("kv_v1/app", map[string]interface{}{"db.user": "app", "limits": map[string]interface{}{"max": 10}})
+	srv.PutSecret("kv_v2/app", map[string]interface{}{"db.user": "app2", "db-password": "s3cr3t"})
+	return vc
+}
+
+func TestClient_SecretsEnv(t *testing.T) {
+	vc := newTestEnvClient(t)
+
+	got, err := vc.SecretsEnv([]string{"kv_v1/app", "kv_v2/app"}, EnvOptions{Prefix: "app_"})
+	want := map[string]string{"APP_DB_USER": "app2", "APP_DB_PASSWORD": "s3cr3t", "APP_LIMITS": `{"max":10}`}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Client.SecretsEnv() = %v, %v, want %v", got, err, want)
+	}
+	if _, err = vc.SecretsEnv([]string{"kv_v2/app", "kv_v2/missing"}, EnvOptions{}); err == nil {
+		t.Errorf("Client.SecretsEnv() expected error for a missing secret")
+	}
+
+	os.Setenv("VAULTLIB_TEST_DB_USER", "env")
+	defer os.Unsetenv("VAULTLIB_TEST_DB_USER")
+	defer os.Unsetenv("VAULTLIB_TEST_DB_PASSWORD")
+	if err = vc.ExportSecretsEnv([]string{"kv_v2/app"}, EnvOptions{Prefix: "VAULTLIB_TEST_"}); err != nil {
+		t.Fatalf("Client.ExportSecretsEnv() error = %v", err)
+	}
+	if os.Getenv("VAULTLIB_TEST_DB_USER") != "env" || os.Getenv("VAULTLIB_TEST_DB_PASSWORD") != "s3cr3t" {
+		t.Errorf("Client.ExportSecretsEnv() env = %v, %v", os.Getenv("VAULTLIB_TEST_DB_USER"), os.Getenv("VAULTLIB_TEST_DB_PASSWORD"))
+	}
+}
+
+// TestExecHelperProcess is the child process of TestClient_ExecWithSecrets
+func TestExecHelperProcess(t *testing.T) {
+	if os.Getenv("VAULTLIB_HELPER_PROCESS") != "1" {
+		return
+	}
+	if os.Getenv("DB_PASSWORD") != "s3cr3t" {
+		os.Exit(1)
+	}
+	code, _ := strconv.Atoi(os.Getenv("VAULTLIB_HELPER_EXIT"))
+	os.Exit(code)
+}
+
+func TestClient_ExecWithSecrets(t *testing.T) {
+	vc := newTestEnvClient(t)
+	os.Setenv("VAULTLIB_HELPER_PROCESS", "1")
+	defer os.Unsetenv("VAULTLIB_HELPER_PROCESS")
+
+	for _, exit := range []int{0, 3} {
+		os.Setenv("VAULTLIB_HELPER_EXIT", strconv.Itoa(exit))
+		got, err := vc.ExecWithSecrets([]string{"kv_v2/app"}, EnvOptions{}, os.Args[0], "-test.run=TestExecHelperProcess")
+		if err != nil || got != exit {
+			t.Errorf("Client.ExecWithSecrets() = %v, %v, want %v", got, err, exit)
+		}
+	}
+	os.Unsetenv("VAULTLIB_HELPER_EXIT")
+
+	if _, err := vc.ExecWithSecrets([]string{"kv_v2/app"}, EnvOptions{}, "vaultlib-command-not-found"); err == nil {
+		t.Errorf("Client.ExecWithSecrets() expected error for a command not found")
+	}
+}
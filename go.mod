@@ -3,6 +3,12 @@ module github.com/mch1307/vaultlib
 require (
 	github.com/hashicorp/go-cleanhttp v0.5.2
 	github.com/pkg/errors v0.9.1
+	golang.org/x/crypto v0.9.0
 )
 
-go 1.17
+require (
+	golang.org/x/sys v0.10.0 // indirect
+	golang.org/x/term v0.10.0 // indirect
+)
+
+go 1.19
@@ -18,6 +18,10 @@ func init() {
 	flag.Parse()
 }
 func TestMain(m *testing.M) {
+	// child process of TestClient_ExecWithSecrets, the parent test already prepared Vault
+	if os.Getenv("VAULTLIB_HELPER_PROCESS") == "1" {
+		os.Exit(m.Run())
+	}
 
 	fmt.Println("Testing with Vault version", vaultVersion)
 	fmt.Println("TestMain: Preparing Vault server")
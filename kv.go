@@ -0,0 +1,58 @@
+package vaultlib
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// PutSecret writes data to the kv secret at path (kv v1 or v2 detected from the mount),
+// replacing the existing secret. With kv v2, a new version is created.
+func (c *Client) PutSecret(path string, data map[string]interface{}) error {
+	kvVersion, kvName, err := c.getKVInfo(path)
+	if err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	var payload interface{} = data
+	if kvVersion == "2" {
+		payload = map[string]interface{}{"data": data}
+	}
+	if err = c.requestData("POST", kvAPIPath(kvVersion, kvName, path), payload, nil); err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	c.invalidateSecret(path)
+	return nil
+}
+
+// ListSecrets returns the keys under the kv path, folders end with "/"
+func (c *Client) ListSecrets(path string) ([]string, error) {
+	var list struct {
+		Keys []string `json:"keys"`
+	}
+	kvVersion, kvName, err := c.getKVInfo(path)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	apiPath := path
+	if kvVersion == "2" {
+		apiPath = kvName + "metadata/" + strings.TrimPrefix(path, kvName)
+	}
+	if err = c.requestData("LIST", apiPath, nil, &list); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return list.Keys, nil
+}
+
+// DeleteSecret deletes the kv secret at path. With kv v2, the latest version is
+// soft deleted: its metadata and previous versions are kept.
+func (c *Client) DeleteSecret(path string) error {
+	kvVersion, kvName, err := c.getKVInfo(path)
+	if err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if err = c.requestData("DELETE", kvAPIPath(kvVersion, kvName, path), nil, nil); err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	c.invalidateSecret(path)
+	return nil
+}
@@ -0,0 +1,55 @@
+package vaultlib
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClient_PutListDeleteSecret(t *testing.T) {
+	srv, vc := newTestVault(t)
+
+	for _, mount := range []string{"kv_v1/", "kv_v2/"} {
+		srv.PutSecret(mount+"app/sub/api", map[string]interface{}{"token": "abcd"})
+		if err := vc.PutSecret(mount+"app/db", map[string]interface{}{"user": "app"}); err != nil {
+			t.Errorf("Client.PutSecret() error = %v", err)
+		}
+		if data, ok := srv.Secret(mount + "app/db"); !ok || !reflect.DeepEqual(data, map[string]interface{}{"user": "app"}) {
+			t.Errorf("Client.PutSecret() written = %v, %v", data, ok)
+		}
+		keys, err := vc.ListSecrets(mount + "app")
+		if err != nil || !reflect.DeepEqual(keys, []string{"db", "sub/"}) {
+			t.Errorf("Client.ListSecrets() = %v, %v", keys, err)
+		}
+		if err = vc.DeleteSecret(mount + "app/db"); err != nil {
+			t.Errorf("Client.DeleteSecret() error = %v", err)
+		}
+		if _, ok := srv.Secret(mount + "app/db"); ok {
+			t.Errorf("Client.DeleteSecret() secret %v not deleted", mount+"app/db")
+		}
+	}
+	var calls []string
+	for _, request := range srv.Requests() {
+		if strings.Contains(request, "/kv_v") {
+			calls = append(calls, request)
+		}
+	}
+	want := []string{
+		"POST /v1/kv_v1/app/db",
+		"LIST /v1/kv_v1/app",
+		"DELETE /v1/kv_v1/app/db",
+		"POST /v1/kv_v2/data/app/db",
+		"LIST /v1/kv_v2/metadata/app",
+		"DELETE /v1/kv_v2/data/app/db",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("Vault calls = %q, want %q", calls, want)
+	}
+
+	if _, err := vc.ListSecrets("kv_v2/missing"); err == nil {
+		t.Errorf("Client.ListSecrets() expected error for a missing path")
+	}
+	if err := vc.PutSecret("unknown/app", map[string]interface{}{}); err == nil {
+		t.Errorf("Client.PutSecret() expected error for a path outside the kv mounts")
+	}
+}
@@ -0,0 +1,213 @@
+package vaultlib
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/scrypt"
+)
+
+// KVBundleFormat identifies the vaultlib encrypted kv export files
+const KVBundleFormat = "vaultlib-kv-bundle"
+
+// kvBundleVersion is the version of the encrypted kv export file format
+const kvBundleVersion = 1
+
+// kv bundle encryption modes
+const (
+	kvBundleScrypt  = "scrypt-aes256-gcm"
+	kvBundleTransit = "transit-aes256-gcm"
+)
+
+// scrypt parameters of the new bundles, and the maximum cost accepted when reading one
+const (
+	kvBundleScryptN    = 1 << 15
+	kvBundleScryptR    = 8
+	kvBundleScryptP    = 1
+	kvBundleScryptMaxN = 1 << 20
+	kvBundleSaltSize   = 16
+)
+
+// KVEncryption holds the kv export encryption key: a Passphrase (derived with scrypt),
+// or a Transit key (TransitKey, on the TransitMount mount point, default "transit")
+// generating the data key.
+type KVEncryption struct {
+	Passphrase   string
+	TransitMount string
+	TransitKey   string
+}
+
+// kvBundle is an encrypted kv export, a JSON document:
+//
+//	{"format":"vaultlib-kv-bundle","version":1,"encryption":"scrypt-aes256-gcm",
+//	 "scrypt":{"n":32768,"r":8,"p":1,"salt":"<base64>"},"nonce":"<base64>","ciphertext":"<base64>"}
+//
+// or, with a Transit key, "encryption":"transit-aes256-gcm" and "transit":{"mount":"transit",
+// "key":"my-key","wrapped_key":"vault:v1:..."} instead of "scrypt".
+//
+// The ciphertext is the export JSON document encrypted with AES-256-GCM, authenticated along with
+// all the other bundle fields: any change is detected on decryption.
+type kvBundle struct {
+	Format     string              `json:"format"`
+	Version    int                 `json:"version"`
+	Encryption string              `json:"encryption"`
+	Scrypt     *kvBundleScryptKDF  `json:"scrypt,omitempty"`
+	Transit    *kvBundleTransitKey `json:"transit,omitempty"`
+	Nonce      []byte              `json:"nonce"`
+	Ciphertext []byte              `json:"ciphertext,omitempty"`
+}
+
+// kvBundleScryptKDF holds the scrypt parameters of a bundle key
+type kvBundleScryptKDF struct {
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+	Salt []byte `json:"salt"`
+}
+
+// kvBundleTransitKey holds the Transit key and the data key of a bundle
+type kvBundleTransitKey struct {
+	Mount      string `json:"mount"`
+	Key        string `json:"key"`
+	WrappedKey string `json:"wrapped_key"`
+}
+
+// EncryptKVExport writes the export encrypted with the passphrase or Transit key of enc,
+// see DecryptKVExport.
+func (c *Client) EncryptKVExport(w io.Writer, export *KVExport, enc KVEncryption) error {
+	var plaintext bytes.Buffer
+	if err := export.Write(&plaintext, false); err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	defer zero(plaintext.Bytes())
+
+	bundle := kvBundle{Format: KVBundleFormat, Version: kvBundleVersion}
+	var key []byte
+	switch {
+	case enc.TransitKey != "":
+		bundle.Encryption = kvBundleTransit
+		mount := enc.TransitMount
+		if mount == "" {
+			mount = "transit"
+		}
+		dataKey, err := c.Transit(mount).GenerateDataKey(enc.TransitKey, 256, nil)
+		if err != nil {
+			return errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		key = dataKey.Plaintext
+		bundle.Transit = &kvBundleTransitKey{Mount: mount, Key: enc.TransitKey, WrappedKey: dataKey.Ciphertext}
+	case enc.Passphrase != "":
+		bundle.Encryption = kvBundleScrypt
+		bundle.Scrypt = &kvBundleScryptKDF{N: kvBundleScryptN, R: kvBundleScryptR, P: kvBundleScryptP, Salt: make([]byte, kvBundleSaltSize)}
+		if _, err := io.ReadFull(rand.Reader, bundle.Scrypt.Salt); err != nil {
+			return errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		var err error
+		if key, err = bundleScryptKey(enc.Passphrase, bundle.Scrypt); err != nil {
+			return errors.Wrap(errors.WithStack(err), errInfo())
+		}
+	default:
+		return errors.New("No passphrase or Transit key provided")
+	}
+	defer zero(key)
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	bundle.Nonce = make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, bundle.Nonce); err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	header, err := json.Marshal(bundle)
+	if err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	bundle.Ciphertext = gcm.Seal(nil, bundle.Nonce, plaintext.Bytes(), header)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return errors.Wrap(errors.WithStack(encoder.Encode(bundle)), errInfo())
+}
+
+// DecryptKVExport reads an export written by EncryptKVExport and verifies its integrity.
+//
+// The passphrase of enc is required for passphrase encrypted bundles. The Transit bundles
+// are decrypted with the Transit key they were encrypted with, the Transit fields of enc are ignored.
+func (c *Client) DecryptKVExport(r io.Reader, enc KVEncryption) (*KVExport, error) {
+	var bundle kvBundle
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if err = json.Unmarshal(content, &bundle); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if bundle.Format != KVBundleFormat {
+		return nil, errors.New("Not a " + KVBundleFormat + " file")
+	}
+	if bundle.Version != kvBundleVersion {
+		return nil, errors.New("Unsupported " + KVBundleFormat + " version " + strconv.Itoa(bundle.Version))
+	}
+
+	var key []byte
+	switch {
+	case bundle.Encryption == kvBundleScrypt && bundle.Scrypt != nil:
+		if enc.Passphrase == "" {
+			return nil, errors.New("The bundle is encrypted with a passphrase, none provided")
+		}
+		if key, err = bundleScryptKey(enc.Passphrase, bundle.Scrypt); err != nil {
+			return nil, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+	case bundle.Encryption == kvBundleTransit && bundle.Transit != nil:
+		if key, err = c.Transit(bundle.Transit.Mount).Decrypt(bundle.Transit.Key, bundle.Transit.WrappedKey, nil); err != nil {
+			return nil, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+	default:
+		return nil, errors.New("Unsupported bundle encryption " + bundle.Encryption)
+	}
+	defer zero(key)
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if len(bundle.Nonce) != gcm.NonceSize() {
+		return nil, errors.New("Invalid bundle nonce size")
+	}
+	ciphertext := bundle.Ciphertext
+	bundle.Ciphertext = nil
+	header, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	plaintext, err := gcm.Open(nil, bundle.Nonce, ciphertext, header)
+	if err != nil {
+		return nil, errors.New("Bundle integrity check failed: wrong key or modified bundle")
+	}
+	defer zero(plaintext)
+
+	export, err := ReadKVExport(bytes.NewReader(plaintext))
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return export, nil
+}
+
+// bundleScryptKey derives the AES-256 key from the passphrase, after checking the parameters
+// bounds: a modified bundle could otherwise require an excessive amount of memory.
+func bundleScryptKey(passphrase string, kdf *kvBundleScryptKDF) ([]byte, error) {
+	if kdf.N > kvBundleScryptMaxN || kdf.R < 1 || kdf.R > 32 || kdf.P < 1 || kdf.P > 16 || len(kdf.Salt) < kvBundleSaltSize {
+		return nil, errors.New("Invalid bundle scrypt parameters")
+	}
+	key, err := scrypt.Key([]byte(passphrase), kdf.Salt, kdf.N, kdf.R, kdf.P, 32)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return key, nil
+}
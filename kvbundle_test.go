@@ -0,0 +1,108 @@
+package vaultlib
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClient_EncryptKVExport(t *testing.T) {
+	dataKey := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+	srv, vc := newTestVault(t)
+	srv.HandleFunc("transit/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/transit/datakey/plaintext/backup":
+			_, _ = w.Write([]byte(`{"data":{"plaintext":"` + dataKey + `","ciphertext":"vault:v1:wrapped"}}`))
+		case "/v1/transit/decrypt/backup":
+			var payload map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			if payload["ciphertext"] != "vault:v1:wrapped" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":{"plaintext":"` + dataKey + `"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	export := &KVExport{Format: KVExportFormat, Version: kvExportVersion, Source: "kv_v2/app/",
+		Secrets: []KVRecord{{Path: "db", Data: map[string]interface{}{"password": "s3cr3t"}}}}
+
+	tests := []struct {
+		name string
+		enc  KVEncryption
+	}{
+		{"passphrase", KVEncryption{Passphrase: "correct horse"}},
+		{"transit", KVEncryption{TransitKey: "backup"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := vc.EncryptKVExport(&buf, export, tt.enc); err != nil {
+				t.Fatalf("Client.EncryptKVExport() error = %v", err)
+			}
+			bundle := buf.String()
+			if strings.Contains(bundle, "s3cr3t") {
+				t.Errorf("Client.EncryptKVExport() bundle contains the plaintext secret")
+			}
+
+			got, err := vc.DecryptKVExport(strings.NewReader(bundle), tt.enc)
+			if err != nil || !reflect.DeepEqual(got, export) {
+				t.Errorf("Client.DecryptKVExport() = %+v, %v, want %+v", got, err, export)
+			}
+
+			// any modification of the bundle is detected
+			var fields map[string]interface{}
+			_ = json.Unmarshal([]byte(bundle), &fields)
+			for _, field := range []string{"ciphertext", "nonce", "scrypt", "transit"} {
+				if fields[field] == nil {
+					continue
+				}
+				modified := make(map[string]interface{})
+				for k, v := range fields {
+					modified[k] = v
+				}
+				switch v := fields[field].(type) {
+				case string:
+					raw, _ := base64.StdEncoding.DecodeString(v)
+					raw[0] ^= 1
+					modified[field] = base64.StdEncoding.EncodeToString(raw)
+				case map[string]interface{}:
+					changed := map[string]interface{}{"extra": 1}
+					for k, e := range v {
+						changed[k] = e
+					}
+					if field == "scrypt" {
+						changed["n"] = 16384
+					} else {
+						changed["key"] = "other"
+					}
+					modified[field] = changed
+				}
+				content, _ := json.Marshal(modified)
+				if _, err = vc.DecryptKVExport(bytes.NewReader(content), tt.enc); err == nil {
+					t.Errorf("Client.DecryptKVExport() expected error for a modified %v", field)
+				}
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := vc.EncryptKVExport(&buf, export, KVEncryption{Passphrase: "correct horse"}); err != nil {
+		t.Fatalf("Client.EncryptKVExport() error = %v", err)
+	}
+	if _, err := vc.DecryptKVExport(bytes.NewReader(buf.Bytes()), KVEncryption{Passphrase: "wrong"}); err == nil {
+		t.Errorf("Client.DecryptKVExport() expected error for a wrong passphrase")
+	}
+	if _, err := vc.DecryptKVExport(bytes.NewReader(buf.Bytes()), KVEncryption{}); err == nil {
+		t.Errorf("Client.DecryptKVExport() expected error without passphrase")
+	}
+	if err := vc.EncryptKVExport(&buf, export, KVEncryption{}); err == nil {
+		t.Errorf("Client.EncryptKVExport() expected error without key")
+	}
+}
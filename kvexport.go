@@ -0,0 +1,353 @@
+package vaultlib
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// KVExportFormat identifies the vaultlib kv export files
+const KVExportFormat = "vaultlib-kv-export"
+
+// kvExportVersion is the version of the kv export file format
+const kvExportVersion = 1
+
+// KVExport holds the secrets of a kv subtree, see ExportKV.
+//
+// It is written as a JSON document:
+//
+//	{"format":"vaultlib-kv-export","version":1,"source":"kv_v2/app/","secrets":[<record>, ...]}
+//
+// or as NDJSON, the header object (without secrets) on the first line followed by a record per line:
+//
+//	{"format":"vaultlib-kv-export","version":1,"source":"kv_v2/app/"}
+//	{"path":"db","data":{"user":"app"},"versions":[...],"custom_metadata":{...}}
+//
+// A record holds the secret path (relative to the source), its latest data, and with kv v2 sources
+// (if requested) its versions and custom metadata.
+type KVExport struct {
+	Format  string     `json:"format"`
+	Version int        `json:"version"`
+	Source  string     `json:"source"`
+	Secrets []KVRecord `json:"secrets,omitempty"`
+}
+
+// KVRecord holds an exported secret.
+//
+// Data is nil if the latest version is deleted or destroyed (kv v2).
+// Versions are ordered by version number, the deleted and destroyed ones have no data.
+type KVRecord struct {
+	Path           string                 `json:"path"`
+	Data           map[string]interface{} `json:"data,omitempty"`
+	Versions       []KVVersion            `json:"versions,omitempty"`
+	CustomMetadata map[string]string      `json:"custom_metadata,omitempty"`
+}
+
+// KVVersion holds an exported kv v2 secret version
+type KVVersion struct {
+	Version     int                    `json:"version"`
+	Data        map[string]interface{} `json:"data,omitempty"`
+	CreatedTime string                 `json:"created_time,omitempty"`
+	Deleted     bool                   `json:"deleted,omitempty"`
+	Destroyed   bool                   `json:"destroyed,omitempty"`
+}
+
+// KVExportOptions holds the kv export options.
+//
+// AllVersions exports all the versions of the kv v2 secrets, Metadata their custom metadata.
+// Both are ignored for kv v1 sources.
+type KVExportOptions struct {
+	AllVersions bool
+	Metadata    bool
+}
+
+// KVImportOptions holds the kv import options.
+//
+// Overwrite replaces the existing secrets, they are skipped otherwise.
+//
+// DryRun reports what would be imported without writing anything.
+type KVImportOptions struct {
+	Overwrite bool
+	DryRun    bool
+}
+
+// KVImportReport lists the target paths created, updated and skipped by ImportKV
+type KVImportReport struct {
+	Created []string
+	Updated []string
+	Skipped []string
+}
+
+// kvMetadata holds the kv v2 metadata response data
+type kvMetadata struct {
+	CurrentVersion int               `json:"current_version"`
+	CustomMetadata map[string]string `json:"custom_metadata"`
+	Versions       map[string]struct {
+		CreatedTime  string `json:"created_time"`
+		DeletionTime string `json:"deletion_time"`
+		Destroyed    bool   `json:"destroyed"`
+	} `json:"versions"`
+}
+
+// ExportKV exports all the secrets under the kv path root (ie "kv_v2/app/" or "kv_v1/")
+// with their paths relative to root. Secrets are read from Vault, not from the secret cache.
+func (c *Client) ExportKV(root string, opts KVExportOptions) (*KVExport, error) {
+	root = strings.TrimSuffix(root, "/") + "/"
+	kvVersion, kvName, err := c.getKVInfo(root)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	paths, err := c.listKVTree(root, "")
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	export := &KVExport{Format: KVExportFormat, Version: kvExportVersion, Source: root, Secrets: []KVRecord{}}
+	for _, path := range paths {
+		record := KVRecord{Path: path}
+		if kvVersion == "2" && (opts.AllVersions || opts.Metadata) {
+			var metadata kvMetadata
+			metadataPath := kvName + "metadata/" + strings.TrimPrefix(root+path, kvName)
+			if err = c.requestData("GET", metadataPath, nil, &metadata); err != nil {
+				return nil, errors.Wrap(errors.WithStack(err), errInfo())
+			}
+			if opts.Metadata {
+				record.CustomMetadata = metadata.CustomMetadata
+			}
+			if opts.AllVersions {
+				if record.Versions, err = c.exportKVVersions(kvName, root+path, metadata); err != nil {
+					return nil, errors.Wrap(errors.WithStack(err), errInfo())
+				}
+			}
+		}
+		if record.Data, err = c.readKVData(kvVersion, kvName, root+path, 0); err != nil && !isNotFound(err) {
+			return nil, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		export.Secrets = append(export.Secrets, record)
+	}
+	return export, nil
+}
+
+// exportKVVersions reads the secret versions listed in metadata
+func (c *Client) exportKVVersions(kvName, path string, metadata kvMetadata) ([]KVVersion, error) {
+	versions := make([]KVVersion, 0, len(metadata.Versions))
+	for number, v := range metadata.Versions {
+		version, err := strconv.Atoi(number)
+		if err != nil {
+			return nil, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		kvv := KVVersion{Version: version, CreatedTime: v.CreatedTime, Deleted: v.DeletionTime != "", Destroyed: v.Destroyed}
+		if !kvv.Deleted && !kvv.Destroyed {
+			if kvv.Data, err = c.readKVData("2", kvName, path, version); err != nil {
+				return nil, errors.Wrap(errors.WithStack(err), errInfo())
+			}
+		}
+		versions = append(versions, kvv)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+	return versions, nil
+}
+
+// listKVTree returns the secret paths under root/folder recursively, relative to root
+func (c *Client) listKVTree(root, folder string) ([]string, error) {
+	keys, err := c.ListSecrets(root + folder)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	sort.Strings(keys)
+	var paths []string
+	for _, key := range keys {
+		if !strings.HasSuffix(key, "/") {
+			paths = append(paths, folder+key)
+			continue
+		}
+		sub, err := c.listKVTree(root, folder+key)
+		if err != nil {
+			return nil, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		paths = append(paths, sub...)
+	}
+	return paths, nil
+}
+
+// readKVData reads the data of the secret version (latest if 0) from Vault
+func (c *Client) readKVData(kvVersion, kvName, path string, version int) (map[string]interface{}, error) {
+	apiPath := kvAPIPath(kvVersion, kvName, path)
+	if version > 0 {
+		apiPath += "?version=" + strconv.Itoa(version)
+	}
+	if kvVersion != "2" {
+		var data map[string]interface{}
+		if err := c.requestData("GET", apiPath, nil, &data); err != nil {
+			return nil, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		return data, nil
+	}
+	var v2Secret vaultSecretKV2
+	if err := c.requestData("GET", apiPath, nil, &v2Secret); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return v2Secret.Data, nil
+}
+
+// ImportKV imports the exported secrets under the kv path root, which may be a kv v1 or v2
+// mount whatever the export source.
+//
+// New secrets are created with all their exported versions (kv v2 target, deleted and destroyed
+// versions excepted), then their custom metadata. Existing secrets are skipped, or with opts.Overwrite
+// updated with the latest data if it differs and with the custom metadata if it differs.
+// Records without data are skipped.
+//
+// ImportKV stops at the first error and returns the report of the secrets imported so far.
+func (c *Client) ImportKV(root string, export *KVExport, opts KVImportOptions) (*KVImportReport, error) {
+	report := new(KVImportReport)
+	root = strings.TrimSuffix(root, "/") + "/"
+	kvVersion, kvName, err := c.getKVInfo(root)
+	if err != nil {
+		return report, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	for _, record := range export.Secrets {
+		path := root + strings.TrimPrefix(record.Path, "/")
+		var writes []map[string]interface{}
+		for _, v := range record.Versions {
+			if v.Data != nil && kvVersion == "2" {
+				writes = append(writes, v.Data)
+			}
+		}
+		if record.Data != nil && (len(writes) == 0 || !reflect.DeepEqual(writes[len(writes)-1], record.Data)) {
+			writes = append(writes, record.Data)
+		}
+		if record.Data == nil || len(writes) == 0 {
+			report.Skipped = append(report.Skipped, path)
+			continue
+		}
+
+		current, err := c.readKVData(kvVersion, kvName, path, 0)
+		if err != nil && !isNotFound(err) {
+			return report, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		exists := err == nil
+		metadataPath := kvName + "metadata/" + strings.TrimPrefix(path, kvName)
+		writeMetadata := kvVersion == "2" && len(record.CustomMetadata) > 0
+		if exists {
+			if !opts.Overwrite {
+				report.Skipped = append(report.Skipped, path)
+				continue
+			}
+			// the data and the custom metadata are compared and updated separately
+			if writeMetadata {
+				var metadata kvMetadata
+				if err = c.requestData("GET", metadataPath, nil, &metadata); err != nil {
+					return report, errors.Wrap(errors.WithStack(err), errInfo())
+				}
+				writeMetadata = !reflect.DeepEqual(metadata.CustomMetadata, record.CustomMetadata)
+			}
+			if reflect.DeepEqual(normalizeKVData(current), normalizeKVData(record.Data)) {
+				writes = nil
+			} else {
+				// only the latest data is written over an existing secret
+				writes = writes[len(writes)-1:]
+			}
+			if len(writes) == 0 && !writeMetadata {
+				report.Skipped = append(report.Skipped, path)
+				continue
+			}
+		}
+
+		if !opts.DryRun {
+			for _, data := range writes {
+				if err = c.PutSecret(path, data); err != nil {
+					return report, errors.Wrap(errors.WithStack(err), errInfo())
+				}
+			}
+			if writeMetadata {
+				payload := map[string]interface{}{"custom_metadata": record.CustomMetadata}
+				if err = c.requestData("POST", metadataPath, payload, nil); err != nil {
+					return report, errors.Wrap(errors.WithStack(err), errInfo())
+				}
+			}
+		}
+		if exists {
+			report.Updated = append(report.Updated, path)
+		} else {
+			report.Created = append(report.Created, path)
+		}
+	}
+	return report, nil
+}
+
+// normalizeKVData returns the data decoded as the exported records are, for comparison
+func normalizeKVData(data map[string]interface{}) interface{} {
+	var normalized interface{}
+	encoded, _ := json.Marshal(data)
+	_ = json.Unmarshal(encoded, &normalized)
+	return normalized
+}
+
+// Write writes the export as a JSON document, or as NDJSON if ndjson (see KVExport)
+func (e *KVExport) Write(w io.Writer, ndjson bool) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if !ndjson {
+		enc.SetIndent("", "  ")
+		return errors.Wrap(errors.WithStack(enc.Encode(e)), errInfo())
+	}
+	header := *e
+	header.Secrets = nil
+	if err := enc.Encode(header); err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	for _, record := range e.Secrets {
+		if err := enc.Encode(record); err != nil {
+			return errors.Wrap(errors.WithStack(err), errInfo())
+		}
+	}
+	return nil
+}
+
+// ReadKVExport reads an export written by KVExport.Write, as a JSON document or NDJSON
+func ReadKVExport(r io.Reader) (*KVExport, error) {
+	var export KVExport
+	dec := json.NewDecoder(r)
+	if err := dec.Decode(&export); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if export.Format != KVExportFormat {
+		return nil, errors.New("Not a " + KVExportFormat + " file")
+	}
+	if export.Version != kvExportVersion {
+		return nil, errors.New("Unsupported " + KVExportFormat + " version " + strconv.Itoa(export.Version))
+	}
+
+	// NDJSON records follow the header
+	for {
+		var record KVRecord
+		err := dec.Decode(&record)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		export.Secrets = append(export.Secrets, record)
+	}
+	for _, record := range export.Secrets {
+		if record.Path == "" {
+			return nil, errors.New("Secret record without path")
+		}
+	}
+	return &export, nil
+}
+
+// isNotFound returns true if Vault responded with a 404
+func isNotFound(err error) bool {
+	rspErr, ok := errors.Cause(err).(*ResponseError)
+	return ok && rspErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,122 @@
+package vaultlib
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClient_ExportImportKV(t *testing.T) {
+	srv, vc := newTestVault(t)
+	srv.PutSecret("kv_v1/app/db", map[string]interface{}{"user": "app", "port": 5432})
+	srv.PutSecret("kv_v1/other/ignored", map[string]interface{}{"key": "value"})
+	srv.PutSecret("kv_v2/app/db", map[string]interface{}{"user": "v1"})
+	srv.PutSecret("kv_v2/app/db", map[string]interface{}{"user": "app2"})
+	srv.PutSecret("kv_v2/app/sub/api", map[string]interface{}{"token": "abcd"})
+	srv.PutSecret("kv_v2/app/sub/deleted", map[string]interface{}{"key": "old"})
+	srv.PutSecret("kv_v2/app/sub/deleted", map[string]interface{}{"key": "gone"})
+	if err := vc.requestData("POST", "kv_v2/metadata/app/sub/api", map[string]interface{}{"custom_metadata": map[string]string{"owner": "team"}}, nil); err != nil {
+		t.Fatalf("custom metadata error = %v", err)
+	}
+	if err := vc.DeleteSecret("kv_v2/app/sub/deleted"); err != nil {
+		t.Fatalf("Client.DeleteSecret() error = %v", err)
+	}
+	// metadata returns the kv v2 metadata of the secret
+	metadata := func(path string) kvMetadata {
+		t.Helper()
+		var m kvMetadata
+		if err := vc.requestData("GET", "kv_v2/metadata/"+path, nil, &m); err != nil {
+			t.Errorf("kv v2 metadata of %v error = %v", path, err)
+		}
+		return m
+	}
+
+	export, err := vc.ExportKV("kv_v2/app", KVExportOptions{AllVersions: true, Metadata: true})
+	if err != nil {
+		t.Fatalf("Client.ExportKV() error = %v", err)
+	}
+	// the creation times are set by the server
+	for i := range export.Secrets {
+		for j, version := range export.Secrets[i].Versions {
+			if version.CreatedTime == "" {
+				t.Errorf("Client.ExportKV() %v version %v without creation time", export.Secrets[i].Path, version.Version)
+			}
+			export.Secrets[i].Versions[j].CreatedTime = ""
+		}
+	}
+	want := []KVRecord{
+		{Path: "db", Data: map[string]interface{}{"user": "app2"}, Versions: []KVVersion{
+			{Version: 1, Data: map[string]interface{}{"user": "v1"}},
+			{Version: 2, Data: map[string]interface{}{"user": "app2"}}}},
+		{Path: "sub/api", Data: map[string]interface{}{"token": "abcd"}, CustomMetadata: map[string]string{"owner": "team"},
+			Versions: []KVVersion{{Version: 1, Data: map[string]interface{}{"token": "abcd"}}}},
+		{Path: "sub/deleted", Versions: []KVVersion{
+			{Version: 1, Data: map[string]interface{}{"key": "old"}},
+			{Version: 2, Deleted: true}}},
+	}
+	if export.Source != "kv_v2/app/" || !reflect.DeepEqual(export.Secrets, want) {
+		t.Errorf("Client.ExportKV() = %+v, want %+v", export.Secrets, want)
+	}
+
+	// both file formats read back the same export
+	for _, ndjson := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err = export.Write(&buf, ndjson); err != nil {
+			t.Fatalf("KVExport.Write() error = %v", err)
+		}
+		if ndjson && strings.Count(buf.String(), "\n") != 4 {
+			t.Errorf("KVExport.Write() ndjson = %v, want 4 lines", buf.String())
+		}
+		got, err := ReadKVExport(&buf)
+		if err != nil || !reflect.DeepEqual(got, export) {
+			t.Errorf("ReadKVExport() ndjson %v = %+v, %v, want %+v", ndjson, got, err, export)
+		}
+	}
+	if _, err = ReadKVExport(strings.NewReader(`{"format":"other"}`)); err == nil {
+		t.Errorf("ReadKVExport() expected error for an unknown format")
+	}
+
+	// dry run to kv v1: db exists, no write
+	report, err := vc.ImportKV("kv_v1/app", export, KVImportOptions{DryRun: true})
+	wantReport := &KVImportReport{Created: []string{"kv_v1/app/sub/api"}, Skipped: []string{"kv_v1/app/db", "kv_v1/app/sub/deleted"}}
+	if _, written := srv.Secret("kv_v1/app/sub/api"); err != nil || !reflect.DeepEqual(report, wantReport) || written {
+		t.Errorf("Client.ImportKV() dry run = %+v, %v, want %+v", report, err, wantReport)
+	}
+
+	report, err = vc.ImportKV("kv_v1/app", export, KVImportOptions{Overwrite: true})
+	wantReport = &KVImportReport{Created: []string{"kv_v1/app/sub/api"}, Updated: []string{"kv_v1/app/db"}, Skipped: []string{"kv_v1/app/sub/deleted"}}
+	if err != nil || !reflect.DeepEqual(report, wantReport) {
+		t.Errorf("Client.ImportKV() kv v1 = %+v, %v, want %+v", report, err, wantReport)
+	}
+	if data, _ := srv.Secret("kv_v1/app/db"); !reflect.DeepEqual(data, map[string]interface{}{"user": "app2"}) {
+		t.Errorf("Client.ImportKV() kv v1 app/db = %v", data)
+	}
+
+	// kv v1 to kv v2: unchanged secrets are skipped even with overwrite, new ones get their versions
+	export, err = vc.ExportKV("kv_v1/app/", KVExportOptions{AllVersions: true})
+	if err != nil || len(export.Secrets) != 2 {
+		t.Fatalf("Client.ExportKV() kv v1 = %+v, %v", export, err)
+	}
+	report, err = vc.ImportKV("kv_v2/copy", &KVExport{Secrets: want}, KVImportOptions{})
+	if err != nil || len(report.Created) != 2 || metadata("copy/db").CurrentVersion != 2 || metadata("copy/sub/api").CustomMetadata["owner"] != "team" {
+		t.Errorf("Client.ImportKV() kv v2 = %+v, %v", report, err)
+	}
+	report, err = vc.ImportKV("kv_v2/copy", export, KVImportOptions{Overwrite: true})
+	wantReport = &KVImportReport{Skipped: []string{"kv_v2/copy/db", "kv_v2/copy/sub/api"}}
+	if err != nil || !reflect.DeepEqual(report, wantReport) || metadata("copy/db").CurrentVersion != 2 {
+		t.Errorf("Client.ImportKV() kv v2 overwrite = %+v, %v, want %+v", report, err, wantReport)
+	}
+
+	// a custom metadata change alone is imported, without a new version
+	want[1].CustomMetadata = map[string]string{"owner": "other"}
+	report, err = vc.ImportKV("kv_v2/copy", &KVExport{Secrets: want}, KVImportOptions{Overwrite: true})
+	wantReport = &KVImportReport{Updated: []string{"kv_v2/copy/sub/api"}, Skipped: []string{"kv_v2/copy/db", "kv_v2/copy/sub/deleted"}}
+	if api := metadata("copy/sub/api"); err != nil || !reflect.DeepEqual(report, wantReport) || api.CurrentVersion != 1 || api.CustomMetadata["owner"] != "other" {
+		t.Errorf("Client.ImportKV() kv v2 metadata = %+v, %v, want %+v", report, err, wantReport)
+	}
+
+	if _, err = vc.ExportKV("kv_v2/missing", KVExportOptions{}); err == nil {
+		t.Errorf("Client.ExportKV() expected error for a missing path")
+	}
+}
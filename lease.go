@@ -0,0 +1,223 @@
+package vaultlib
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Lease holds the Vault lease of a dynamic secret
+type Lease struct {
+	ID        string
+	Duration  time.Duration
+	Renewable bool
+}
+
+// leaseFromResponse returns the lease of the Vault response
+func leaseFromResponse(rsp vaultResponse) Lease {
+	return Lease{
+		ID:        rsp.LeaseID,
+		Duration:  time.Duration(rsp.LeaseDuration) * time.Second,
+		Renewable: rsp.Renewable,
+	}
+}
+
+// renewLease extends the lease by increment, returns the renewed lease
+func (c *Client) renewLease(leaseID string, increment time.Duration) (Lease, error) {
+	url := c.address.String() + "/v1/sys/leases/renew"
+	payload := map[string]interface{}{"lease_id": leaseID}
+	if increment > 0 {
+		payload["increment"] = int(increment.Seconds())
+	}
+
+	req, err := c.newRequest("PUT", url)
+	if err != nil {
+		return Lease{}, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if err = req.setJSONBody(payload); err != nil {
+		return Lease{}, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	rsp, err := req.execute()
+	if err != nil {
+		return Lease{}, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return leaseFromResponse(rsp), nil
+}
+
+// LeaseInfo holds the lease information returned by Vault lookup
+type LeaseInfo struct {
+	ID          string     `json:"id"`
+	IssueTime   time.Time  `json:"issue_time"`
+	ExpireTime  *time.Time `json:"expire_time"`
+	LastRenewal *time.Time `json:"last_renewal"`
+	Renewable   bool       `json:"renewable"`
+	TTL         int        `json:"ttl"`
+}
+
+// LookupLease returns the information of the lease
+func (c *Client) LookupLease(leaseID string) (*LeaseInfo, error) {
+	var leaseInfo LeaseInfo
+	url := c.address.String() + "/v1/sys/leases/lookup"
+
+	req, err := c.newRequest("PUT", url)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if err = req.setJSONBody(map[string]string{"lease_id": leaseID}); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	rsp, err := req.execute()
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if err = json.Unmarshal([]byte(rsp.Data), &leaseInfo); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return &leaseInfo, nil
+}
+
+// RenewLease extends the lease by increment (or the secret engine default if 0),
+// returns the renewed lease
+func (c *Client) RenewLease(leaseID string, increment time.Duration) (Lease, error) {
+	return c.renewLease(leaseID, increment)
+}
+
+// RevokeLease revokes the lease, the secret is invalidated immediately
+func (c *Client) RevokeLease(leaseID string) error {
+	return c.revokeLease("revoke", map[string]string{"lease_id": leaseID})
+}
+
+// RevokeLeasePrefix revokes all the leases issued under the prefix (ie "database/creds/my-role/").
+//
+// Requires sudo capability on sys/leases/revoke-prefix.
+func (c *Client) RevokeLeasePrefix(prefix string) error {
+	return c.revokeLease("revoke-prefix/"+prefix, nil)
+}
+
+// RevokeLeaseForce revokes all the leases issued under the prefix, ignoring the secret
+// engine revocation errors: the leases are removed from Vault even if the secrets are still valid.
+//
+// Requires sudo capability on sys/leases/revoke-force.
+func (c *Client) RevokeLeaseForce(prefix string) error {
+	return c.revokeLease("revoke-force/"+prefix, nil)
+}
+
+func (c *Client) revokeLease(endpoint string, payload interface{}) error {
+	url := c.address.String() + "/v1/sys/leases/" + endpoint
+
+	req, err := c.newRequest("PUT", url)
+	if err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if payload != nil {
+		if err = req.setJSONBody(payload); err != nil {
+			return errors.Wrap(errors.WithStack(err), errInfo())
+		}
+	}
+
+	if _, err = req.execute(); err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return nil
+}
+
+// LeaseWatcher renews a lease in the background until its max TTL is near
+type LeaseWatcher struct {
+	client        *Client
+	lease         Lease
+	increment     time.Duration
+	renewFraction float64
+	renewed       chan Lease
+	done          chan error
+	stop          chan struct{}
+	stopOnce      sync.Once
+}
+
+// WatchLease starts renewing the lease in the background, each time renewFraction
+// (ie 0.66, default 2/3 if not in ]0, 1[) of its duration has elapsed.
+//
+// The renewed leases are sent on the Renewed channel. A value is sent on the Done channel
+// when the secret must be replaced: nil when the lease can no longer be extended
+// (not renewable or max TTL near), the renewal error otherwise. The watcher stops afterwards.
+func (c *Client) WatchLease(lease Lease, renewFraction float64) *LeaseWatcher {
+	if renewFraction <= 0 || renewFraction >= 1 {
+		renewFraction = 2.0 / 3.0
+	}
+	w := &LeaseWatcher{
+		client:        c,
+		lease:         lease,
+		increment:     lease.Duration,
+		renewFraction: renewFraction,
+		renewed:       make(chan Lease, 1),
+		done:          make(chan error, 1),
+		stop:          make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Renewed returns the channel receiving the lease after each renewal.
+// Only the latest renewal is kept if the channel is not read.
+func (w *LeaseWatcher) Renewed() <-chan Lease {
+	return w.renewed
+}
+
+// Done returns the channel receiving a value when the secret must be replaced
+func (w *LeaseWatcher) Done() <-chan error {
+	return w.done
+}
+
+// Stop stops the lease renewal, the lease is not revoked
+func (w *LeaseWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+	})
+}
+
+func (w *LeaseWatcher) run() {
+	for {
+		if w.lease.Duration <= 0 {
+			w.done <- nil
+			return
+		}
+		wait := time.Duration(float64(w.lease.Duration) * w.renewFraction)
+		timer := time.NewTimer(wait)
+		select {
+		case <-w.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if !w.lease.Renewable || w.lease.ID == "" {
+			w.done <- nil
+			return
+		}
+
+		lease, err := w.client.renewLease(w.lease.ID, w.increment)
+		if err != nil {
+			w.done <- errors.Wrap(errors.WithStack(err), errInfo())
+			return
+		}
+		w.lease = lease
+		select {
+		case w.renewed <- lease:
+		default:
+			select {
+			case <-w.renewed:
+			default:
+			}
+			w.renewed <- lease
+		}
+
+		// Vault caps the renewal to the max TTL: the lease can not be extended much longer
+		if lease.Duration < w.increment {
+			w.done <- nil
+			return
+		}
+	}
+}
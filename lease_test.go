@@ -0,0 +1,116 @@
+package vaultlib
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_WatchLease(t *testing.T) {
+	var renewals int32
+	srv, vc := newTestVault(t)
+	srv.HandleFunc("sys/leases/", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		payload := make(map[string]interface{})
+		_ = json.Unmarshal(body, &payload)
+		if r.URL.Path != "/v1/sys/leases/renew" || payload["lease_id"] != "database/creds/my-role/abcd" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// second renewal is capped by the max TTL
+		duration := 2
+		if atomic.AddInt32(&renewals, 1) > 1 {
+			duration = 1
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"lease_id": payload["lease_id"], "lease_duration": duration, "renewable": true})
+	})
+
+	tests := []struct {
+		name         string
+		lease        Lease
+		wantRenewals int
+		wantErr      bool
+	}{
+		{"maxTTL", Lease{ID: "database/creds/my-role/abcd", Duration: 2 * time.Second, Renewable: true}, 2, false},
+		{"notRenewable", Lease{ID: "database/creds/my-role/abcd", Duration: 2 * time.Second}, 0, false},
+		{"renewError", Lease{ID: "unknown", Duration: 2 * time.Second, Renewable: true}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&renewals, 0)
+			watcher := vc.WatchLease(tt.lease, 0.25)
+			defer watcher.Stop()
+			select {
+			case err := <-watcher.Done():
+				if (err != nil) != tt.wantErr {
+					t.Errorf("LeaseWatcher.Done() error = %v, wantErr %v", err, tt.wantErr)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("LeaseWatcher.Done() not signaled")
+			}
+			if got := int(atomic.LoadInt32(&renewals)); got != tt.wantRenewals {
+				t.Errorf("LeaseWatcher renewals = %v, want %v", got, tt.wantRenewals)
+			}
+			if tt.wantRenewals > 0 {
+				if lease := <-watcher.Renewed(); lease.Duration != time.Second {
+					t.Errorf("LeaseWatcher.Renewed() = %v, want the latest renewal", lease)
+				}
+			}
+		})
+	}
+}
+
+func TestClient_LeaseAPI(t *testing.T) {
+	var calls []string
+	srv, vc := newTestVault(t)
+	srv.HandleFunc("sys/leases/", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		payload := make(map[string]interface{})
+		_ = json.Unmarshal(body, &payload)
+		if r.Method != "PUT" || (payload["lease_id"] != nil && payload["lease_id"] != "database/creds/my-role/abcd") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		calls = append(calls, r.URL.Path)
+		switch r.URL.Path {
+		case "/v1/sys/leases/lookup":
+			_, _ = w.Write([]byte(`{"data":{"id":"database/creds/my-role/abcd","issue_time":"2017-07-24T10:48:31.589738434-04:00",
+				"expire_time":"2017-07-25T10:48:31.589738434-04:00","last_renewal":null,"renewable":true,"ttl":86200}}`))
+		case "/v1/sys/leases/renew":
+			_, _ = w.Write([]byte(`{"lease_id":"database/creds/my-role/abcd","renewable":true,"lease_duration":60}`))
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+
+	info, err := vc.LookupLease("database/creds/my-role/abcd")
+	if err != nil || info.TTL != 86200 || info.ExpireTime == nil || info.LastRenewal != nil || !info.Renewable {
+		t.Errorf("Client.LookupLease() = %v, error = %v", info, err)
+	}
+	if _, err = vc.LookupLease("unknown"); err == nil {
+		t.Errorf("Client.LookupLease() of unknown lease should fail")
+	}
+	lease, err := vc.RenewLease("database/creds/my-role/abcd", time.Minute)
+	if err != nil || lease != (Lease{"database/creds/my-role/abcd", time.Minute, true}) {
+		t.Errorf("Client.RenewLease() = %v, error = %v", lease, err)
+	}
+	if err = vc.RevokeLease("database/creds/my-role/abcd"); err != nil {
+		t.Errorf("Client.RevokeLease() error = %v", err)
+	}
+	if err = vc.RevokeLeasePrefix("database/creds/my-role/"); err != nil {
+		t.Errorf("Client.RevokeLeasePrefix() error = %v", err)
+	}
+	if err = vc.RevokeLeaseForce("database/creds/"); err != nil {
+		t.Errorf("Client.RevokeLeaseForce() error = %v", err)
+	}
+	want := []string{"/v1/sys/leases/lookup", "/v1/sys/leases/renew", "/v1/sys/leases/revoke",
+		"/v1/sys/leases/revoke-prefix/database/creds/my-role/", "/v1/sys/leases/revoke-force/database/creds/"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("Vault calls = %v, want %v", calls, want)
+	}
+}
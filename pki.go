@@ -0,0 +1,347 @@
+package vaultlib
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// PKI holds the PKI secrets engine client
+type PKI struct {
+	client     *Client
+	mountPoint string
+}
+
+// PKICertificateRequest holds the parameters of a certificate issuance or signature.
+//
+// TTL is the requested certificate TTL (ie "72h"), role default if empty.
+type PKICertificateRequest struct {
+	CommonName        string
+	AltNames          []string
+	IPSANs            []string
+	URISANs           []string
+	TTL               string
+	ExcludeCNFromSANs bool
+}
+
+// PKICertificate holds a certificate issued or signed by Vault, parsed and PEM encoded.
+//
+// PrivateKey is only set for issued certificates.
+type PKICertificate struct {
+	Certificate    *x509.Certificate
+	PrivateKey     crypto.Signer
+	IssuingCA      *x509.Certificate
+	CAChain        []*x509.Certificate
+	SerialNumber   string
+	CertificatePEM string
+	PrivateKeyPEM  string
+	IssuingCAPEM   string
+	CAChainPEM     []string
+}
+
+// pkiRequest holds the PKI request payload
+type pkiRequest struct {
+	CommonName        string `json:"common_name,omitempty"`
+	AltNames          string `json:"alt_names,omitempty"`
+	IPSANs            string `json:"ip_sans,omitempty"`
+	URISANs           string `json:"uri_sans,omitempty"`
+	TTL               string `json:"ttl,omitempty"`
+	ExcludeCNFromSANs bool   `json:"exclude_cn_from_sans,omitempty"`
+	CSR               string `json:"csr,omitempty"`
+	Format            string `json:"format"`
+}
+
+// pkiResponse holds the PKI response data
+type pkiResponse struct {
+	Certificate    string   `json:"certificate"`
+	IssuingCA      string   `json:"issuing_ca"`
+	CAChain        []string `json:"ca_chain"`
+	PrivateKey     string   `json:"private_key"`
+	SerialNumber   string   `json:"serial_number"`
+	RevocationTime int64    `json:"revocation_time"`
+}
+
+// PKI returns a client for the PKI secrets engine mounted at mountPoint (default "pki")
+func (c *Client) PKI(mountPoint string) *PKI {
+	if mountPoint == "" {
+		mountPoint = "pki"
+	}
+	return &PKI{client: c, mountPoint: mountPoint}
+}
+
+// Issue generates a new private key and certificate for the role
+func (p *PKI) Issue(role string, certReq *PKICertificateRequest) (*PKICertificate, error) {
+	var rsp pkiResponse
+	if err := p.do("POST", "issue/"+role, newPKIRequest(certReq), &rsp); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	cert, err := rsp.parse()
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return cert, nil
+}
+
+// Sign signs the PEM encoded CSR with the role, certReq overrides the CSR common name and SANs.
+//
+// Use GenerateCSR to create the CSR and its private key locally.
+func (p *PKI) Sign(role string, csrPEM []byte, certReq *PKICertificateRequest) (*PKICertificate, error) {
+	var rsp pkiResponse
+	payload := newPKIRequest(certReq)
+	payload.CSR = string(csrPEM)
+	if payload.CommonName == "" {
+		if csr, err := parseCSR(csrPEM); err == nil {
+			payload.CommonName = csr.Subject.CommonName
+		}
+	}
+
+	if err := p.do("POST", "sign/"+role, payload, &rsp); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	cert, err := rsp.parse()
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return cert, nil
+}
+
+// CA returns the CA certificate of the mount
+func (p *PKI) CA() (*x509.Certificate, error) {
+	var rsp pkiResponse
+	if err := p.do("GET", "cert/ca", nil, &rsp); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	cert, err := parseCertificatePEM(rsp.Certificate)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return cert, nil
+}
+
+// CAChain returns the CA chain of the mount (empty for a root CA)
+func (p *PKI) CAChain() ([]*x509.Certificate, error) {
+	var rsp pkiResponse
+	if err := p.do("GET", "cert/ca_chain", nil, &rsp); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	chain, err := parseCertificatesPEM(rsp.Certificate)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return chain, nil
+}
+
+// Revoke revokes the certificate with the serial number (ie "39:dd:2e:..."),
+// returns the revocation time
+func (p *PKI) Revoke(serialNumber string) (time.Time, error) {
+	var rsp pkiResponse
+	payload := map[string]string{"serial_number": serialNumber}
+	if err := p.do("POST", "revoke", payload, &rsp); err != nil {
+		return time.Time{}, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return time.Unix(rsp.RevocationTime, 0), nil
+}
+
+// CRL returns the current certificate revocation list of the mount
+func (p *PKI) CRL() (*x509.RevocationList, error) {
+	der, err := p.client.RawRequest("GET", "/v1/"+p.mountPoint+"/crl", nil)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	crl, err := x509.ParseRevocationList(der)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return crl, nil
+}
+
+// TLSCertificate returns the certificate, its chain and private key as a tls.Certificate
+func (pc *PKICertificate) TLSCertificate() (tls.Certificate, error) {
+	if pc.PrivateKey == nil {
+		return tls.Certificate{}, errors.New("No private key for certificate " + pc.SerialNumber)
+	}
+	tlsCert := tls.Certificate{
+		Certificate: [][]byte{pc.Certificate.Raw},
+		PrivateKey:  pc.PrivateKey,
+		Leaf:        pc.Certificate,
+	}
+	for _, ca := range pc.CAChain {
+		tlsCert.Certificate = append(tlsCert.Certificate, ca.Raw)
+	}
+	if len(pc.CAChain) == 0 && pc.IssuingCA != nil {
+		tlsCert.Certificate = append(tlsCert.Certificate, pc.IssuingCA.Raw)
+	}
+	return tlsCert, nil
+}
+
+// GenerateCSR generates a private key ("rsa" 2048 bits or "ec" P-256) and a PEM encoded CSR
+// for the request common name and SANs
+func GenerateCSR(keyType string, certReq *PKICertificateRequest) ([]byte, crypto.Signer, error) {
+	var key crypto.Signer
+	var err error
+	if certReq == nil {
+		return nil, nil, errors.New("No certificate request provided")
+	}
+
+	switch keyType {
+	case "rsa", "":
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "ec":
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return nil, nil, errors.New("Unsupported key type " + keyType)
+	}
+	if err != nil {
+		return nil, nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	template := &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: certReq.CommonName},
+		DNSNames: certReq.AltNames,
+	}
+	for _, ip := range certReq.IPSANs {
+		template.IPAddresses = append(template.IPAddresses, net.ParseIP(ip))
+	}
+	for _, uri := range certReq.URISANs {
+		u, err := url.Parse(uri)
+		if err != nil {
+			return nil, nil, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		template.URIs = append(template.URIs, u)
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}), key, nil
+}
+
+// do sends the request to the PKI path, parses the response data into out (if not nil)
+func (p *PKI) do(method, path string, payload, out interface{}) error {
+	return p.client.requestData(method, p.mountPoint+"/"+path, payload, out)
+}
+
+// newPKIRequest returns a request payload initialized with the certificate request
+func newPKIRequest(certReq *PKICertificateRequest) *pkiRequest {
+	payload := &pkiRequest{Format: "pem"}
+	if certReq != nil {
+		payload.CommonName = certReq.CommonName
+		payload.AltNames = strings.Join(certReq.AltNames, ",")
+		payload.IPSANs = strings.Join(certReq.IPSANs, ",")
+		payload.URISANs = strings.Join(certReq.URISANs, ",")
+		payload.TTL = certReq.TTL
+		payload.ExcludeCNFromSANs = certReq.ExcludeCNFromSANs
+	}
+	return payload
+}
+
+// parse returns the parsed certificate, CA chain and private key of the response
+func (rsp pkiResponse) parse() (*PKICertificate, error) {
+	var err error
+	pc := &PKICertificate{
+		SerialNumber:   rsp.SerialNumber,
+		CertificatePEM: rsp.Certificate,
+		PrivateKeyPEM:  rsp.PrivateKey,
+		IssuingCAPEM:   rsp.IssuingCA,
+		CAChainPEM:     rsp.CAChain,
+	}
+	if pc.Certificate, err = parseCertificatePEM(rsp.Certificate); err != nil {
+		return nil, err
+	}
+	if rsp.IssuingCA != "" {
+		if pc.IssuingCA, err = parseCertificatePEM(rsp.IssuingCA); err != nil {
+			return nil, err
+		}
+	}
+	for _, caPEM := range rsp.CAChain {
+		ca, err := parseCertificatePEM(caPEM)
+		if err != nil {
+			return nil, err
+		}
+		pc.CAChain = append(pc.CAChain, ca)
+	}
+	if rsp.PrivateKey != "" {
+		if pc.PrivateKey, err = parsePrivateKeyPEM(rsp.PrivateKey); err != nil {
+			return nil, err
+		}
+	}
+	return pc, nil
+}
+
+// parseCertificatePEM returns the first certificate of the PEM data
+func parseCertificatePEM(data string) (*x509.Certificate, error) {
+	certs, err := parseCertificatesPEM(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("No certificate found in PEM data")
+	}
+	return certs[0], nil
+}
+
+// parseCertificatesPEM returns all the certificates of the PEM data
+func parseCertificatesPEM(data string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return certs, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		certs = append(certs, cert)
+	}
+}
+
+// parsePrivateKeyPEM returns the PKCS1, EC or PKCS8 encoded private key
+func parsePrivateKeyPEM(data string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("No private key found in PEM data")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("Unsupported private key type")
+	}
+	return signer, nil
+}
+
+// parseCSR returns the parsed PEM encoded certificate request
+func parseCSR(csrPEM []byte) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(csrPEM)
+	if block == nil {
+		return nil, errors.New("No certificate request found in PEM data")
+	}
+	return x509.ParseCertificateRequest(block.Bytes)
+}
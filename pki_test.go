@@ -0,0 +1,134 @@
+package vaultlib
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"testing"
+	"time"
+)
+
+func TestPKI_Issue(t *testing.T) {
+	conf := NewConfig()
+	conf.Token = "my-dev-root-vault-token"
+	vc, _ := NewClient(conf)
+	pki := vc.PKI("")
+
+	tests := []struct {
+		name    string
+		role    string
+		certReq *PKICertificateRequest
+		wantErr bool
+	}{
+		{"rsa", "my-pki-role", &PKICertificateRequest{CommonName: "my-service.vaultlib.test",
+			AltNames: []string{"alt.vaultlib.test"}, IPSANs: []string{"127.0.0.1"}, TTL: "1h"}, false},
+		{"ec", "my-ec-pki-role", &PKICertificateRequest{CommonName: "my-ec-service.vaultlib.test"}, false},
+		{"notAllowed", "my-pki-role", &PKICertificateRequest{CommonName: "example.com"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pki.Issue(tt.role, tt.certReq)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PKI.Issue() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Certificate.Subject.CommonName != tt.certReq.CommonName || got.SerialNumber == "" ||
+				got.PrivateKey == nil || got.IssuingCA == nil {
+				t.Errorf("PKI.Issue() = %v", got)
+			}
+			if err = got.Certificate.CheckSignatureFrom(got.IssuingCA); err != nil {
+				t.Errorf("PKI.Issue() certificate not signed by issuing CA: %v", err)
+			}
+			if _, err = got.TLSCertificate(); err != nil {
+				t.Errorf("PKICertificate.TLSCertificate() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestPKI_SignRevoke(t *testing.T) {
+	conf := NewConfig()
+	conf.Token = "my-dev-root-vault-token"
+	vc, _ := NewClient(conf)
+	pki := vc.PKI("pki")
+
+	certReq := &PKICertificateRequest{CommonName: "my-csr.vaultlib.test", AltNames: []string{"my-csr.vaultlib.test"}}
+	csr, key, err := GenerateCSR("rsa", certReq)
+	if err != nil {
+		t.Fatalf("GenerateCSR() error = %v", err)
+	}
+	if _, ok := key.(*rsa.PrivateKey); !ok {
+		t.Errorf("GenerateCSR() key = %T, want *rsa.PrivateKey", key)
+	}
+	signed, err := pki.Sign("my-pki-role", csr, nil)
+	if err != nil {
+		t.Fatalf("PKI.Sign() error = %v", err)
+	}
+	if signed.Certificate.Subject.CommonName != "my-csr.vaultlib.test" || signed.PrivateKey != nil {
+		t.Errorf("PKI.Sign() = %v", signed)
+	}
+	if !signed.Certificate.PublicKey.(*rsa.PublicKey).Equal(key.Public()) {
+		t.Errorf("PKI.Sign() certificate public key does not match the CSR key")
+	}
+
+	ca, err := pki.CA()
+	if err != nil || ca.Subject.CommonName != "vaultlib.test" {
+		t.Errorf("PKI.CA() = %v, error = %v", ca, err)
+	}
+	if _, err = pki.CAChain(); err != nil {
+		t.Errorf("PKI.CAChain() error = %v", err)
+	}
+
+	revocationTime, err := pki.Revoke(signed.SerialNumber)
+	if err != nil || time.Since(revocationTime) > time.Minute {
+		t.Errorf("PKI.Revoke() = %v, error = %v", revocationTime, err)
+	}
+	crl, err := pki.CRL()
+	if err != nil {
+		t.Fatalf("PKI.CRL() error = %v", err)
+	}
+	found := false
+	for _, revoked := range crl.RevokedCertificates {
+		if revoked.SerialNumber.Cmp(signed.Certificate.SerialNumber) == 0 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("PKI.CRL() does not contain the revoked certificate %v", signed.SerialNumber)
+	}
+}
+
+func TestGenerateCSR(t *testing.T) {
+	tests := []struct {
+		name    string
+		keyType string
+		certReq *PKICertificateRequest
+		wantErr bool
+	}{
+		{"ec", "ec", &PKICertificateRequest{CommonName: "a.vaultlib.test", IPSANs: []string{"10.0.0.1"},
+			URISANs: []string{"spiffe://vaultlib.test/a"}}, false},
+		{"badKeyType", "dsa", &PKICertificateRequest{CommonName: "a.vaultlib.test"}, true},
+		{"noRequest", "ec", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csrPEM, key, err := GenerateCSR(tt.keyType, tt.certReq)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GenerateCSR() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			csr, err := parseCSR(csrPEM)
+			if err != nil || csr.Subject.CommonName != tt.certReq.CommonName || len(csr.IPAddresses) != 1 || len(csr.URIs) != 1 {
+				t.Errorf("GenerateCSR() csr = %v, error = %v", csr, err)
+			}
+			if _, ok := key.(*ecdsa.PrivateKey); !ok {
+				t.Errorf("GenerateCSR() key = %T, want *ecdsa.PrivateKey", key)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package vaultlib
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// certificateRetryInterval is the maximum delay between two issuance attempts after a failure
+var certificateRetryInterval = 30 * time.Second
+
+// CertificateRotator serves a certificate issued from a PKI role and re-issues it
+// in the background before it expires.
+type CertificateRotator struct {
+	sync.RWMutex
+	pki           *PKI
+	role          string
+	certReq       PKICertificateRequest
+	renewFraction float64
+	cert          *PKICertificate
+	tlsCert       *tls.Certificate
+	caPool        *x509.CertPool
+	stop          chan struct{}
+	stopOnce      sync.Once
+}
+
+// NewCertificateRotator issues a certificate for the role and re-issues it in the background
+// each time renewFraction (ie 0.66, default 2/3 if not in ]0, 1[) of its lifetime has elapsed.
+//
+// Call Stop to stop the background re-issuance.
+func (p *PKI) NewCertificateRotator(role string, certReq *PKICertificateRequest, renewFraction float64) (*CertificateRotator, error) {
+	if certReq == nil {
+		return nil, errors.New("No certificate request provided")
+	}
+	if renewFraction <= 0 || renewFraction >= 1 {
+		renewFraction = 2.0 / 3.0
+	}
+	r := &CertificateRotator{
+		pki:           p,
+		role:          role,
+		certReq:       *certReq,
+		renewFraction: renewFraction,
+		stop:          make(chan struct{}),
+	}
+	if err := r.issue(); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	// the CA pool is built from the first certificate issuing CA and chain
+	r.caPool = x509.NewCertPool()
+	if r.cert.IssuingCA != nil {
+		r.caPool.AddCert(r.cert.IssuingCA)
+	}
+	for _, ca := range r.cert.CAChain {
+		r.caPool.AddCert(ca)
+	}
+
+	go r.rotate()
+	return r, nil
+}
+
+// TLSConfig returns a tls.Config serving the current certificate, as server (GetCertificate)
+// and as client (GetClientCertificate), trusting the PKI CA (RootCAs and ClientCAs).
+//
+// ClientAuth is set to tls.RequireAndVerifyClientCert (mTLS), adjust it if needed.
+func (r *CertificateRotator) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:           tls.VersionTLS12,
+		GetCertificate:       r.GetCertificate,
+		GetClientCertificate: r.GetClientCertificate,
+		RootCAs:              r.caPool,
+		ClientCAs:            r.caPool,
+		ClientAuth:           tls.RequireAndVerifyClientCert,
+	}
+}
+
+// GetCertificate returns the current certificate, for use as tls.Config GetCertificate
+func (r *CertificateRotator) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.RLock()
+	defer r.RUnlock()
+	return r.tlsCert, nil
+}
+
+// GetClientCertificate returns the current certificate, for use as tls.Config GetClientCertificate
+func (r *CertificateRotator) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.RLock()
+	defer r.RUnlock()
+	return r.tlsCert, nil
+}
+
+// Certificate returns the current certificate
+func (r *CertificateRotator) Certificate() *PKICertificate {
+	r.RLock()
+	defer r.RUnlock()
+	return r.cert
+}
+
+// Stop stops the certificate re-issuance, the current certificate is still served
+func (r *CertificateRotator) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+}
+
+// issue issues a new certificate and swaps the current one
+func (r *CertificateRotator) issue() error {
+	cert, err := r.pki.Issue(r.role, &r.certReq)
+	if err != nil {
+		return err
+	}
+	tlsCert, err := cert.TLSCertificate()
+	if err != nil {
+		return err
+	}
+	r.Lock()
+	r.cert = cert
+	r.tlsCert = &tlsCert
+	r.Unlock()
+	return nil
+}
+
+// rotate re-issues the certificate before it expires, launched at rotator creation time as a go routine
+func (r *CertificateRotator) rotate() {
+	for {
+		leaf := r.Certificate().Certificate
+		lifetime := leaf.NotAfter.Sub(leaf.NotBefore)
+		wait := time.Until(leaf.NotBefore.Add(time.Duration(float64(lifetime) * r.renewFraction)))
+
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-time.After(wait):
+			}
+			err := r.issue()
+			if err == nil {
+				r.pki.client.setStatus("certificate re-issued")
+				break
+			}
+			// retry while the current certificate is still valid
+			r.pki.client.setStatus("Error re-issuing certificate " + err.Error())
+			wait = time.Until(leaf.NotAfter) / 10
+			if wait > certificateRetryInterval || wait <= 0 {
+				wait = certificateRetryInterval
+			}
+		}
+	}
+}
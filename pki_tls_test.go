@@ -0,0 +1,52 @@
+package vaultlib
+
+import (
+	"crypto/tls"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPKI_NewCertificateRotator(t *testing.T) {
+	conf := NewConfig()
+	conf.Token = "my-dev-root-vault-token"
+	vc, _ := NewClient(conf)
+	pki := vc.PKI("")
+
+	if _, err := pki.NewCertificateRotator("my-pki-role", &PKICertificateRequest{CommonName: "example.com"}, 0); err == nil {
+		t.Errorf("PKI.NewCertificateRotator() with not allowed common name should fail")
+	}
+
+	// Vault backdates certificates by 30s: re-issued after 0.9 * 35s
+	rotator, err := pki.NewCertificateRotator("my-pki-role",
+		&PKICertificateRequest{CommonName: "my-service.vaultlib.test", TTL: "5s"}, 0.9)
+	if err != nil {
+		t.Fatalf("PKI.NewCertificateRotator() error = %v", err)
+	}
+	defer rotator.Stop()
+	first := rotator.Certificate().SerialNumber
+
+	serverConf := rotator.TLSConfig()
+	clientConf := rotator.TLSConfig()
+	clientConf.ServerName = "my-service.vaultlib.test"
+	serverConn, clientConn := net.Pipe()
+	serverErr := make(chan error, 1)
+	go func() {
+		server := tls.Server(serverConn, serverConf)
+		serverErr <- server.Handshake()
+		server.Close()
+	}()
+	client := tls.Client(clientConn, clientConf)
+	if err = client.Handshake(); err != nil {
+		t.Errorf("mTLS client handshake error = %v", err)
+	}
+	if err = <-serverErr; err != nil {
+		t.Errorf("mTLS server handshake error = %v", err)
+	}
+	client.Close()
+
+	time.Sleep(4 * time.Second)
+	if second := rotator.Certificate().SerialNumber; second == first {
+		t.Errorf("CertificateRotator certificate not re-issued, serial still %v", first)
+	}
+}
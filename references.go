@@ -0,0 +1,270 @@
+package vaultlib
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// referencePrefix is the prefix of the secret references
+const referencePrefix = "vault://"
+
+// resolveReferencesConcurrency is the maximum number of secrets read concurrently by ResolveReferences
+const resolveReferencesConcurrency = 4
+
+// referencePattern matches the secret references, ending at a whitespace or quote
+var referencePattern = regexp.MustCompile(`vault://[^\s"'<>]+`)
+
+// UnresolvedReferencesError lists the secret references that could not be resolved,
+// with the cause of each failure
+type UnresolvedReferencesError struct {
+	Errors map[string]error
+}
+
+func (e *UnresolvedReferencesError) Error() string {
+	refs := make([]string, 0, len(e.Errors))
+	for ref := range e.Errors {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+
+	msgs := make([]string, len(refs))
+	for i, ref := range refs {
+		msgs[i] = ref + ": " + e.Errors[ref].Error()
+	}
+	return "Could not resolve " + strconv.Itoa(len(refs)) + " secret references: " + strings.Join(msgs, "; ")
+}
+
+// secretReference is a parsed vault://<path>[#key][?version=N] reference
+type secretReference struct {
+	path    string
+	key     string
+	version int
+}
+
+// secretVersion identifies a secret read, shared by the references to the same secret
+type secretVersion struct {
+	path    string
+	version int
+}
+
+// ResolveReferences replaces the secret references found in the strings of target
+// with the secret values. target is a pointer (to a string, struct, map, slice...) or a map,
+// walked recursively: exported struct fields, map values, slice and array elements.
+//
+// A reference is vault://<path>[#key][?version=N] (ie vault://kv_v2/app/db#password),
+// it may be a whole string or be embedded in a string, ending at a whitespace or quote.
+// Without key, the whole secret is inserted JSON encoded. Without version, the latest one is used.
+//
+// Each secret is read once with GetSecretVersion, whatever the number of references to it.
+// The unresolved references are left unchanged and reported in an *UnresolvedReferencesError.
+func (c *Client) ResolveReferences(target interface{}) error {
+	v := reflect.ValueOf(target)
+	if (v.Kind() != reflect.Ptr && v.Kind() != reflect.Map) || v.IsNil() {
+		return errors.New("ResolveReferences target must be a non nil pointer or map")
+	}
+
+	// collect the references, then read the secrets and replace the references
+	refs := make(map[string]struct{})
+	walkStrings(v, func(s string) string {
+		for _, ref := range referencePattern.FindAllString(s, -1) {
+			refs[ref] = struct{}{}
+		}
+		return s
+	})
+	if len(refs) == 0 {
+		return nil
+	}
+
+	values, errs := c.resolveReferences(refs)
+	walkStrings(v, func(s string) string {
+		return referencePattern.ReplaceAllStringFunc(s, func(ref string) string {
+			if value, ok := values[ref]; ok {
+				return value
+			}
+			return ref
+		})
+	})
+
+	if len(errs) > 0 {
+		return errors.WithStack(&UnresolvedReferencesError{Errors: errs})
+	}
+	return nil
+}
+
+// ResolveString returns s with its secret references replaced by the secret values, see ResolveReferences
+func (c *Client) ResolveString(s string) (string, error) {
+	err := c.ResolveReferences(&s)
+	return s, err
+}
+
+// resolveReferences reads the referenced secrets, returns the value of each resolved reference
+// and the error of the others
+func (c *Client) resolveReferences(refs map[string]struct{}) (map[string]string, map[string]error) {
+	values := make(map[string]string, len(refs))
+	errs := make(map[string]error)
+	parsed := make(map[string]secretReference, len(refs))
+	reads := make(map[secretVersion]bool)
+	for ref := range refs {
+		sr, err := parseReference(ref)
+		if err != nil {
+			errs[ref] = err
+			continue
+		}
+		parsed[ref] = sr
+		reads[secretVersion{path: sr.path, version: sr.version}] = true
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	secrets := make(map[secretVersion]Secret, len(reads))
+	secretErrs := make(map[secretVersion]error)
+	sem := make(chan struct{}, resolveReferencesConcurrency)
+	for sv := range reads {
+		wg.Add(1)
+		go func(sv secretVersion) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			secret, err := c.GetSecretVersion(sv.path, sv.version)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				secretErrs[sv] = err
+				return
+			}
+			secrets[sv] = secret
+		}(sv)
+	}
+	wg.Wait()
+
+	for ref, sr := range parsed {
+		sv := secretVersion{path: sr.path, version: sr.version}
+		if err := secretErrs[sv]; err != nil {
+			errs[ref] = err
+			continue
+		}
+		value, err := referenceValue(secrets[sv], sr.key)
+		if err != nil {
+			errs[ref] = err
+			continue
+		}
+		values[ref] = value
+	}
+	return values, errs
+}
+
+// parseReference parses a vault://<path>[#key][?version=N] reference, key and version in any order
+func parseReference(ref string) (secretReference, error) {
+	var sr secretReference
+	var query string
+	s := strings.TrimPrefix(ref, referencePrefix)
+
+	if i := strings.Index(s, "?"); i >= 0 {
+		s, query = s[:i], s[i+1:]
+		if j := strings.Index(query, "#"); j >= 0 {
+			s, query = s+query[j:], query[:j]
+		}
+	}
+	if i := strings.Index(s, "#"); i >= 0 {
+		s, sr.key = s[:i], s[i+1:]
+	}
+	sr.path = strings.Trim(s, "/")
+	if sr.path == "" {
+		return sr, errors.New("No secret path in reference")
+	}
+
+	if query != "" {
+		params, err := url.ParseQuery(query)
+		if err != nil {
+			return sr, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		for param := range params {
+			if param != "version" {
+				return sr, errors.New("Unsupported reference parameter " + param)
+			}
+		}
+		if sr.version, err = strconv.Atoi(params.Get("version")); err != nil || sr.version < 0 {
+			return sr, errors.New("Invalid secret version " + params.Get("version"))
+		}
+	}
+	return sr, nil
+}
+
+// referenceValue returns the value of the secret key, the JSON encoded secret if key is empty
+func referenceValue(secret Secret, key string) (string, error) {
+	if key == "" {
+		if secret.JSONSecret != nil {
+			return string(secret.JSONSecret), nil
+		}
+		value, err := json.Marshal(secret.KV)
+		return string(value), err
+	}
+	// JSON secrets values which are not strings are inserted JSON encoded
+	fields, err := secretFields(secret)
+	if err != nil {
+		return "", errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if value, ok := fields[key]; ok {
+		return value, nil
+	}
+	return "", errors.New("Key " + key + " not found in secret")
+}
+
+// walkStrings replaces the strings reachable from v with fn result
+func walkStrings(v reflect.Value, fn func(string) string) {
+	walkStringsVisited(v, fn, make(map[uintptr]bool))
+}
+
+func walkStringsVisited(v reflect.Value, fn func(string) string, visited map[uintptr]bool) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || visited[v.Pointer()] {
+			return
+		}
+		visited[v.Pointer()] = true
+		walkStringsVisited(v.Elem(), fn, visited)
+	case reflect.Interface:
+		if v.IsNil() || !v.CanSet() {
+			return
+		}
+		// the value held by an interface can not be modified, walk a copy
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		walkStringsVisited(elem, fn, visited)
+		v.Set(elem)
+	case reflect.String:
+		if v.CanSet() {
+			v.SetString(fn(v.String()))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if field := v.Field(i); field.CanSet() {
+				walkStringsVisited(field, fn, visited)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			walkStringsVisited(v.Index(i), fn, visited)
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return
+		}
+		// map values can not be modified in place, walk a copy and store it back
+		for _, key := range v.MapKeys() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			walkStringsVisited(elem, fn, visited)
+			v.SetMapIndex(key, elem)
+		}
+	}
+}
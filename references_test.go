@@ -0,0 +1,122 @@
+package vaultlib
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+type referencesTestDB struct {
+	User     string
+	Password string
+	Port     int
+	private  string
+}
+
+type referencesTestConfig struct {
+	DB       referencesTestDB
+	DSN      string
+	Replicas []*referencesTestDB
+	Extra    map[string]interface{}
+	Token    *string
+}
+
+func TestClient_ResolveReferences(t *testing.T) {
+	srv, vc := newTestVault(t)
+	srv.PutSecret("kv_v2/app/db", map[string]interface{}{"user": "app", "password": "old"})
+	srv.PutSecret("kv_v2/app/db", map[string]interface{}{"user": "app", "password": "s3cr3t"})
+	srv.PutSecret("kv_v1/app/json", map[string]interface{}{"token": "abcd", "limits": map[string]interface{}{"max": 10}})
+
+	token := "vault://kv_v1/app/json#token"
+	config := referencesTestConfig{
+		DB: referencesTestDB{User: "vault://kv_v2/app/db#user", Password: "vault://kv_v2/app/db#password",
+			private: "vault://kv_v2/app/db#password"},
+		DSN:      "user=vault://kv_v2/app/db#user password=vault://kv_v2/app/db?version=1#password host=db",
+		Replicas: []*referencesTestDB{{Password: "vault://kv_v2/app/db#password?version=1"}},
+		Extra: map[string]interface{}{
+			"limits":  "vault://kv_v1/app/json#limits",
+			"list":    []interface{}{"vault://kv_v1/app/json#token", 1},
+			"nested":  map[string]string{"user": "vault://kv_v2/app/db#user"},
+			"missing": "vault://kv_v2/app/missing#key",
+			"badKey":  "vault://kv_v2/app/db#nope",
+			"plain":   "no reference",
+		},
+		Token: &token,
+	}
+
+	err := vc.ResolveReferences(&config)
+	unresolved, ok := errors.Cause(err).(*UnresolvedReferencesError)
+	if !ok || len(unresolved.Errors) != 2 ||
+		unresolved.Errors["vault://kv_v2/app/missing#key"] == nil || unresolved.Errors["vault://kv_v2/app/db#nope"] == nil {
+		t.Fatalf("Client.ResolveReferences() error = %v, want 2 unresolved references", err)
+	}
+
+	want := referencesTestConfig{
+		DB:       referencesTestDB{User: "app", Password: "s3cr3t", private: "vault://kv_v2/app/db#password"},
+		DSN:      "user=app password=old host=db",
+		Replicas: []*referencesTestDB{{Password: "old"}},
+		Extra: map[string]interface{}{
+			"limits":  `{"max":10}`,
+			"list":    []interface{}{"abcd", 1},
+			"nested":  map[string]string{"user": "app"},
+			"missing": "vault://kv_v2/app/missing#key",
+			"badKey":  "vault://kv_v2/app/db#nope",
+			"plain":   "no reference",
+		},
+	}
+	if *config.Token != "abcd" {
+		t.Errorf("Client.ResolveReferences() Token = %v", *config.Token)
+	}
+	config.Token = nil
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("Client.ResolveReferences() = %+v, want %+v", config, want)
+	}
+	// app/db, app/db version 1, app/json and app/missing
+	var reads int
+	for _, request := range srv.Requests() {
+		if strings.HasPrefix(request, "GET /v1/kv_v") {
+			reads++
+		}
+	}
+	if reads != 4 {
+		t.Errorf("Client.ResolveReferences() Vault reads = %v, want 4", reads)
+	}
+
+	got, err := vc.ResolveString("vault://kv_v2/app/db")
+	if err != nil || got != `{"password":"s3cr3t","user":"app"}` {
+		t.Errorf("Client.ResolveString() = %v, %v", got, err)
+	}
+	if err = vc.ResolveReferences(config); err == nil {
+		t.Errorf("Client.ResolveReferences() expected error for a struct value target")
+	}
+}
+
+func Test_parseReference(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    secretReference
+		wantErr bool
+	}{
+		{"vault://kv/app", secretReference{path: "kv/app"}, false},
+		{"vault://kv/app#key", secretReference{path: "kv/app", key: "key"}, false},
+		{"vault://kv/app#key?version=3", secretReference{path: "kv/app", key: "key", version: 3}, false},
+		{"vault://kv/app?version=3#key", secretReference{path: "kv/app", key: "key", version: 3}, false},
+		{"vault://kv/app?version=x", secretReference{}, true},
+		{"vault://kv/app?other=1", secretReference{}, true},
+		{"vault://#key", secretReference{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := parseReference(tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseReference() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseReference() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -16,6 +18,8 @@ type request struct {
 	HTTPClient *http.Client
 	Headers    http.Header
 	Token      string
+	client     *Client
+	body       []byte
 }
 
 // RawRequest create and execute http request against Vault HTTP API for client.
@@ -52,6 +56,26 @@ func (c *Client) RawRequest(method, path string, payload interface{}) (result js
 	return rsp, nil
 }
 
+// requestData sends the request to the Vault path (without /v1/ prefix), parses the
+// response data into out (if not nil).
+// In case of error, the response data is still parsed if Vault returned any (ie batch results).
+func (c *Client) requestData(method, path string, payload, out interface{}) error {
+	var vaultRsp vaultResponse
+
+	body, err := c.RawRequest(method, "/v1/"+path, payload)
+	if out != nil && len(body) > 0 {
+		if jsonErr := json.Unmarshal(body, &vaultRsp); jsonErr == nil && len(vaultRsp.Data) > 0 {
+			if jsonErr = json.Unmarshal([]byte(vaultRsp.Data), out); jsonErr != nil && err == nil {
+				err = jsonErr
+			}
+		}
+	}
+	if err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return nil
+}
+
 // Returns a ready to execute request
 func (c *Client) newRequest(method, url string) (*request, error) {
 	var err error
@@ -73,6 +97,7 @@ func (c *Client) newRequest(method, url string) (*request, error) {
 	if len(c.namespace) > 0 {
 		req.Req.Header.Set("X-Vault-Namespace", c.namespace)
 	}
+	req.client = c
 	return req, errors.Wrap(errors.WithStack(err), errInfo())
 
 }
@@ -83,11 +108,44 @@ func (r *request) setJSONBody(val interface{}) error {
 	if err != nil {
 		return errors.Wrap(errors.WithStack(err), errInfo())
 	}
+	r.body = buf
 	r.Req.Body = ioutil.NopCloser(bytes.NewReader(buf))
 	return nil
 
 }
 
+// Asks Vault to wrap the response in a single use token valid for ttl
+func (r *request) setWrapTTL(ttl time.Duration) {
+	r.Req.Header.Set("X-Vault-Wrap-TTL", strconv.Itoa(int(ttl.Seconds())))
+}
+
+// ResponseError is returned when Vault responds with an unexpected http status.
+//
+// Use errors.Cause (github.com/pkg/errors) to get it from the returned errors.
+type ResponseError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	Body       []byte
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("Vault http call %v returned %v. Body: %v", e.URL, e.Status, string(e.Body))
+}
+
+// KVMountNotFoundError is returned when the path is not on a kv mount visible by the
+// client token: the mount does not exist, is not a kv mount, or the token is not allowed
+// to access it (Vault only lists the mounts the token has access to).
+//
+// Use errors.Cause (github.com/pkg/errors) to get it from the returned errors.
+type KVMountNotFoundError struct {
+	Path string
+}
+
+func (e *KVMountNotFoundError) Error() string {
+	return "Could not get kv version: no kv mount visible for path " + e.Path
+}
+
 // vaultResponse holds the generic json response from Vault server
 type vaultResponse struct {
 	RequestID     string          `json:"request_id"`
@@ -108,6 +166,11 @@ func (r *request) execute() (vaultResponse, error) {
 		return vaultRsp, errors.Wrap(errors.WithStack(err), errInfo())
 	}
 
+	// Vault responds with no content (204) to some calls (ie revoke)
+	if len(res) == 0 {
+		return vaultRsp, nil
+	}
+
 	jsonErr := json.Unmarshal(res, &vaultRsp)
 	if jsonErr != nil {
 		return vaultRsp, errors.Wrap(errors.WithStack(err), errInfo())
@@ -117,24 +180,53 @@ func (r *request) execute() (vaultResponse, error) {
 
 }
 
-// Executes the raw request, does not parse Vault response
+// Executes the raw request, does not parse Vault response.
+// In case of 403, retries once if the client's token file contains a new token
+// (only if the request uses the client's token).
 func (r *request) executeRaw() ([]byte, error) {
-	res, err := r.HTTPClient.Do(r.Req)
+	body, res, err := r.send()
 	if err != nil {
-		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+		return body, err
 	}
-	defer res.Body.Close()
 
-	body, readErr := ioutil.ReadAll(res.Body)
-	if readErr != nil {
-		return body, errors.Wrap(errors.WithStack(err), errInfo())
+	if res.StatusCode == http.StatusForbidden && r.client != nil &&
+		r.Req.Header.Get("X-Vault-Token") == r.client.getTokenID() {
+		if swapped, _ := r.client.reloadTokenFile(); swapped {
+			r.Req.Header.Set("X-Vault-Token", r.client.getTokenID())
+			body, res, err = r.send()
+			if err != nil {
+				return body, err
+			}
+		}
 	}
 
-	if res.StatusCode != http.StatusOK {
-		httpErr := fmt.Sprintf("Vault http call %v returned %v. Body: %v", r.Req.URL.String(), res.Status, string(body))
-		return body, errors.New(httpErr)
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent {
+		return body, errors.WithStack(&ResponseError{
+			URL:        r.Req.URL.String(),
+			StatusCode: res.StatusCode,
+			Status:     res.Status,
+			Body:       body,
+		})
 	}
 
 	return body, nil
 
 }
+
+// Sends the http request and reads the response body
+func (r *request) send() ([]byte, *http.Response, error) {
+	if r.body != nil {
+		r.Req.Body = ioutil.NopCloser(bytes.NewReader(r.body))
+	}
+	res, err := r.HTTPClient.Do(r.Req)
+	if err != nil {
+		return nil, nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	defer res.Body.Close()
+
+	body, readErr := ioutil.ReadAll(res.Body)
+	if readErr != nil {
+		return body, res, errors.Wrap(errors.WithStack(readErr), errInfo())
+	}
+	return body, res, nil
+}
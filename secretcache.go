@@ -0,0 +1,245 @@
+package vaultlib
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// secretCacheStaleRetryInterval is the delay between two Vault reads while serving a stale secret
+var secretCacheStaleRetryInterval = 5 * time.Second
+
+// SecretCacheConfig holds the secret cache configuration.
+//
+// TTL is the cache duration of the secrets (default 1 minute). The secrets returned with a lease
+// (lease ID) or a kv v1 secret with a "ttl" key are cached for their lease duration instead.
+// The lease duration returned by Vault for the other kv v1 secrets (the mount default, 768h) is ignored.
+//
+// PathTTL overrides the cache duration of the given secret paths, a zero duration disables the cache for the path.
+//
+// MaxStale is the duration an expired secret is still served when Vault can not be reached
+// (connection error or 5xx status, ie sealed). Stale secrets are not served if 0.
+type SecretCacheConfig struct {
+	TTL      time.Duration
+	PathTTL  map[string]time.Duration
+	MaxStale time.Duration
+}
+
+// SecretCache is the in-memory cache of the secrets read with GetSecret and GetSecretVersion.
+//
+// Concurrent reads of a secret missing from the cache trigger a single Vault read.
+type SecretCache struct {
+	sync.Mutex
+	config     SecretCacheConfig
+	entries    map[secretCacheKey]*secretCacheEntry
+	calls      map[secretCacheKey]*secretCacheCall
+	generation uint64
+	client     *Client
+}
+
+type secretCacheKey struct {
+	path    string
+	version int
+}
+
+type secretCacheEntry struct {
+	secret     Secret
+	expires    time.Time
+	staleUntil time.Time
+}
+
+// secretCacheCall is a Vault read in progress, shared by the concurrent callers
+type secretCacheCall struct {
+	done   chan struct{}
+	secret Secret
+	err    error
+}
+
+// EnableSecretCache enables the secret cache, replacing the existing one (if any).
+func (c *Client) EnableSecretCache(config SecretCacheConfig) *SecretCache {
+	if config.TTL <= 0 {
+		config.TTL = time.Minute
+	}
+	cache := &SecretCache{
+		config:  config,
+		entries: make(map[secretCacheKey]*secretCacheEntry),
+		calls:   make(map[secretCacheKey]*secretCacheCall),
+		client:  c,
+	}
+	c.withLockContext(func() {
+		c.secretCache = cache
+	})
+	return cache
+}
+
+// DisableSecretCache disables the secret cache, the secrets are read from Vault on each call
+func (c *Client) DisableSecretCache() {
+	c.withLockContext(func() {
+		c.secretCache = nil
+	})
+}
+
+// getSecretCache returns the secret cache, nil if not enabled
+func (c *Client) getSecretCache() *SecretCache {
+	var cache *SecretCache
+	c.withLockContext(func() {
+		cache = c.secretCache
+	})
+	return cache
+}
+
+// invalidateSecret removes the secret from the cache (if enabled)
+func (c *Client) invalidateSecret(path string) {
+	if cache := c.getSecretCache(); cache != nil {
+		cache.Invalidate(path)
+	}
+}
+
+// invalidateTokenSecrets removes the secrets bound to the client token (cubbyhole)
+// from the cache (if enabled), called when the token is replaced or revoked
+func (c *Client) invalidateTokenSecrets() {
+	if cache := c.getSecretCache(); cache != nil {
+		cache.invalidatePrefix(cubbyholeMount)
+	}
+}
+
+// Invalidate removes all the cached versions of the secret, the next read fetches it from Vault
+func (sc *SecretCache) Invalidate(path string) {
+	sc.Lock()
+	defer sc.Unlock()
+	sc.generation++
+	for key := range sc.entries {
+		if key.path == path {
+			delete(sc.entries, key)
+		}
+	}
+}
+
+// invalidatePrefix removes all the cached secrets whose path starts with prefix
+func (sc *SecretCache) invalidatePrefix(prefix string) {
+	sc.Lock()
+	defer sc.Unlock()
+	sc.generation++
+	for key := range sc.entries {
+		if strings.HasPrefix(key.path, prefix) {
+			delete(sc.entries, key)
+		}
+	}
+}
+
+// Purge removes all the cached secrets
+func (sc *SecretCache) Purge() {
+	sc.Lock()
+	defer sc.Unlock()
+	sc.generation++
+	sc.entries = make(map[secretCacheKey]*secretCacheEntry)
+}
+
+// Len returns the number of cached secrets, expired ones included
+func (sc *SecretCache) Len() int {
+	sc.Lock()
+	defer sc.Unlock()
+	return len(sc.entries)
+}
+
+// get returns the cached secret, fetches it if missing or expired
+func (sc *SecretCache) get(path string, version int, fetch func(string, int) (Secret, error)) (Secret, error) {
+	key := secretCacheKey{path: path, version: version}
+
+	sc.Lock()
+	entry, cached := sc.entries[key]
+	if cached && time.Now().Before(entry.expires) {
+		sc.Unlock()
+		return entry.secret.copy(), nil
+	}
+	if call, ok := sc.calls[key]; ok {
+		sc.Unlock()
+		<-call.done
+		return call.secret.copy(), call.err
+	}
+	call := &secretCacheCall{done: make(chan struct{})}
+	sc.calls[key] = call
+	generation := sc.generation
+	sc.Unlock()
+
+	secret, err := fetch(path, version)
+	stale := false
+
+	sc.Lock()
+	delete(sc.calls, key)
+	if err == nil {
+		// the secret is not cached if invalidated while being fetched
+		if ttl := sc.ttl(path, secret); ttl > 0 && generation == sc.generation {
+			expires := time.Now().Add(ttl)
+			sc.entries[key] = &secretCacheEntry{secret: secret, expires: expires, staleUntil: expires.Add(sc.config.MaxStale)}
+		}
+	} else if cached && isUnreachable(err) && time.Now().Before(entry.staleUntil) {
+		secret, err = entry.secret, nil
+		stale = true
+		// retry later rather than on each read while Vault is unreachable
+		entry.expires = time.Now().Add(secretCacheStaleRetryInterval)
+	}
+	sc.Unlock()
+
+	call.secret, call.err = secret, err
+	close(call.done)
+	if stale {
+		sc.client.setStatus("Vault unreachable, serving stale secret " + path)
+	}
+	if err != nil {
+		return secret, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return secret.copy(), nil
+}
+
+// ttl returns the cache duration of the secret
+func (sc *SecretCache) ttl(path string, secret Secret) time.Duration {
+	if ttl, ok := sc.config.PathTTL[path]; ok {
+		return ttl
+	}
+	// every kv v1 read returns a lease duration, the mount default without "ttl" key
+	if secret.Lease.Duration > 0 && (secret.Lease.ID != "" || secret.hasTTLKey()) {
+		return secret.Lease.Duration
+	}
+	return sc.config.TTL
+}
+
+// hasTTLKey returns true if the secret has a "ttl" key, setting the kv v1 lease duration
+func (s Secret) hasTTLKey() bool {
+	if _, ok := s.KV["ttl"]; ok {
+		return true
+	}
+	var raw map[string]json.RawMessage
+	return json.Unmarshal(s.JSONSecret, &raw) == nil && raw["ttl"] != nil
+}
+
+// copy returns a copy of the secret, the cached secrets can not be modified by the callers
+func (s Secret) copy() Secret {
+	cp := s
+	if s.KV != nil {
+		cp.KV = make(map[string]string, len(s.KV))
+		for k, v := range s.KV {
+			cp.KV[k] = v
+		}
+	}
+	if s.JSONSecret != nil {
+		cp.JSONSecret = append([]byte(nil), s.JSONSecret...)
+	}
+	return cp
+}
+
+// isUnreachable returns true if the error means that Vault can not serve the request:
+// connection error or 5xx status (ie sealed, standby)
+func isUnreachable(err error) bool {
+	switch cause := errors.Cause(err).(type) {
+	case *ResponseError:
+		return cause.StatusCode >= 500
+	case *url.Error:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package vaultlib
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSecretCache(t *testing.T) {
+	var reads, status int32
+	srv, vc := newTestVault(t)
+	srv.PutSecret("kv_v2/my-secret", map[string]interface{}{"version": "1"})
+	srv.PutSecret("kv_v2/my-secret", map[string]interface{}{"version": "2"})
+	srv.HandleFunc("kv_v1/leased", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"lease_duration":1,"data":{"key":"value","ttl":"1s"}}`))
+	})
+	srv.SetErrorHook(func(r *http.Request) int {
+		if !strings.HasPrefix(r.URL.Path, "/v1/kv_v") {
+			return 0
+		}
+		atomic.AddInt32(&reads, 1)
+		return int(atomic.LoadInt32(&status))
+	})
+	cache := vc.EnableSecretCache(SecretCacheConfig{
+		TTL:      time.Hour,
+		PathTTL:  map[string]time.Duration{"kv_v2/my-secret": 200 * time.Millisecond},
+		MaxStale: time.Hour,
+	})
+	expectReads := func(want int32) {
+		t.Helper()
+		if got := atomic.SwapInt32(&reads, 0); got != want {
+			t.Errorf("Vault reads = %v, want %v", got, want)
+		}
+	}
+
+	// cached per version, the returned secret can be modified safely
+	secret, err := vc.GetSecret("kv_v2/my-secret")
+	if err != nil || secret.KV["version"] != "2" {
+		t.Fatalf("Client.GetSecret() = %v, %v", secret.KV, err)
+	}
+	secret.KV["version"] = "modified"
+	if secret, _ = vc.GetSecret("kv_v2/my-secret"); secret.KV["version"] != "2" {
+		t.Errorf("Client.GetSecret() returned the modified cached secret %v", secret.KV)
+	}
+	if secret, err = vc.GetSecretVersion("kv_v2/my-secret", 1); err != nil || secret.KV["version"] != "1" {
+		t.Errorf("Client.GetSecretVersion() = %v, %v", secret.KV, err)
+	}
+	expectReads(2)
+	if cache.Len() != 2 {
+		t.Errorf("SecretCache.Len() = %v, want 2", cache.Len())
+	}
+
+	// path TTL and lease duration
+	_, _ = vc.GetSecret("kv_v1/leased")
+	time.Sleep(250 * time.Millisecond)
+	_, _ = vc.GetSecret("kv_v2/my-secret")
+	_, _ = vc.GetSecret("kv_v1/leased")
+	expectReads(2)
+	time.Sleep(800 * time.Millisecond)
+	_, _ = vc.GetSecret("kv_v1/leased")
+	expectReads(1)
+
+	// invalidation
+	cache.Invalidate("kv_v2/my-secret")
+	_, _ = vc.GetSecret("kv_v2/my-secret")
+	_, _ = vc.GetSecretVersion("kv_v2/my-secret", 1)
+	expectReads(2)
+
+	// stale secret served when Vault is unavailable, not when the secret is not found
+	time.Sleep(250 * time.Millisecond)
+	atomic.StoreInt32(&status, http.StatusServiceUnavailable)
+	if secret, err = vc.GetSecret("kv_v2/my-secret"); err != nil || secret.KV == nil {
+		t.Errorf("Client.GetSecret() expected stale secret, got %v, %v", secret.KV, err)
+	}
+	_, _ = vc.GetSecret("kv_v2/my-secret")
+	expectReads(1)
+	cache.Purge()
+	if _, err = vc.GetSecret("kv_v2/my-secret"); err == nil {
+		t.Errorf("Client.GetSecret() expected error after purge")
+	}
+	atomic.StoreInt32(&status, http.StatusNotFound)
+	if _, err = vc.GetSecret("kv_v1/leased"); err == nil {
+		t.Errorf("Client.GetSecret() expected error for a secret not found")
+	}
+	expectReads(2)
+
+	vc.DisableSecretCache()
+	atomic.StoreInt32(&status, 0)
+	_, _ = vc.GetSecret("kv_v1/leased")
+	_, _ = vc.GetSecret("kv_v1/leased")
+	expectReads(2)
+}
+
+func TestSecretCache_kvV1TTL(t *testing.T) {
+	srv, vc := newTestVault(t)
+	srv.PutSecret("kv_v1/plain", map[string]interface{}{"key": "value"})
+	vc.EnableSecretCache(SecretCacheConfig{TTL: 200 * time.Millisecond})
+	reads := func() int {
+		var n int
+		for _, request := range srv.Requests() {
+			if request == "GET /v1/kv_v1/plain" {
+				n++
+			}
+		}
+		return n
+	}
+
+	// the kv v1 mount default lease duration does not override the cache TTL
+	_, _ = vc.GetSecret("kv_v1/plain")
+	_, _ = vc.GetSecret("kv_v1/plain")
+	if got := reads(); got != 1 {
+		t.Errorf("Vault reads = %v, want 1", got)
+	}
+	time.Sleep(250 * time.Millisecond)
+	if secret, err := vc.GetSecret("kv_v1/plain"); err != nil || secret.KV["key"] != "value" || reads() != 2 {
+		t.Errorf("Client.GetSecret() after TTL = %v, %v, reads %v, want 2", secret.KV, err, reads())
+	}
+}
+
+func TestSecretCache_singleflight(t *testing.T) {
+	var reads int32
+	release := make(chan struct{})
+	srv, vc := newTestVault(t)
+	srv.HandleFunc("kv_v1/my-secret", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&reads, 1)
+		<-release
+		_, _ = w.Write([]byte(`{"data":{"key":"value"}}`))
+	})
+	vc.EnableSecretCache(SecretCacheConfig{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if secret, err := vc.GetSecret("kv_v1/my-secret"); err != nil || secret.KV["key"] != "value" {
+				t.Errorf("Client.GetSecret() = %v, %v", secret.KV, err)
+			}
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if reads := atomic.LoadInt32(&reads); reads != 1 {
+		t.Errorf("Vault reads = %v, want 1", reads)
+	}
+}
+
+func TestSecretCache_tokenChange(t *testing.T) {
+	srv, vc := newTestVault(t)
+	srv.PutSecret("kv_v1/my-secret", map[string]interface{}{"key": "value"})
+	srv.HandleFunc("cubbyhole/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"token":"` + r.Header.Get("X-Vault-Token") + `"}}`))
+	})
+	newToken := srv.CreateToken(0)
+	vc.EnableSecretCache(SecretCacheConfig{})
+	_, _ = vc.GetSecret("kv_v1/my-secret")
+	_, _ = vc.GetSecret("cubbyhole/my-secret")
+
+	// the cubbyhole secrets belong to the token, they are not served to the new one
+	dir, err := ioutil.TempDir("", "vaultlib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tokenPath := filepath.Join(dir, "token")
+	_ = ioutil.WriteFile(tokenPath, []byte(newToken), 0600)
+	vc.tokenFile = &tokenFile{path: tokenPath}
+	if swapped, err := vc.swapTokenFromFile(); !swapped || err != nil {
+		t.Fatalf("Client.swapTokenFromFile() = %v, %v", swapped, err)
+	}
+	if got := vc.getSecretCache().Len(); got != 1 {
+		t.Errorf("SecretCache.Len() = %v, want the kv secret only", got)
+	}
+	if secret, err := vc.GetSecret("cubbyhole/my-secret"); err != nil || secret.KV["token"] != newToken {
+		t.Errorf("Client.GetSecret() = %v, %v, want the secret of the new token", secret.KV, err)
+	}
+
+	// nor once the token is revoked
+	if err = vc.RevokeSelf(); err != nil {
+		t.Fatalf("Client.RevokeSelf() error = %v", err)
+	}
+	if got := vc.getSecretCache().Len(); got != 1 {
+		t.Errorf("SecretCache.Len() after RevokeSelf = %v, want the kv secret only", got)
+	}
+}
@@ -0,0 +1,195 @@
+package vaultlib
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// SSH holds the SSH secrets engine client
+type SSH struct {
+	client     *Client
+	mountPoint string
+}
+
+// SSHSignOptions holds the parameters of a public key signature.
+//
+// CertType is "user" or "host", Vault default ("user") if empty.
+//
+// TTL is the requested certificate TTL (ie "30m"), role default if empty.
+type SSHSignOptions struct {
+	ValidPrincipals []string
+	CertType        string
+	KeyID           string
+	TTL             string
+	CriticalOptions map[string]string
+	Extensions      map[string]string
+}
+
+// SSHCertificate holds an OpenSSH certificate signed by Vault.
+//
+// SignedKey is the certificate in authorized_keys format, as expected in an id_*-cert.pub file.
+//
+// ValidBefore is zero if the certificate never expires.
+type SSHCertificate struct {
+	SignedKey       []byte
+	Type            string
+	Serial          uint64
+	SerialNumber    string
+	CertType        string
+	KeyID           string
+	ValidPrincipals []string
+	ValidAfter      time.Time
+	ValidBefore     time.Time
+	CriticalOptions map[string]string
+	Extensions      map[string]string
+}
+
+// SSHCredentials holds the one-time password generated by the SSH secrets engine
+type SSHCredentials struct {
+	Key      string `json:"key"`
+	KeyType  string `json:"key_type"`
+	Username string `json:"username"`
+	IP       string `json:"ip"`
+	Port     int    `json:"port"`
+	Lease    Lease  `json:"-"`
+}
+
+// sshSignRequest holds the SSH sign request payload
+type sshSignRequest struct {
+	PublicKey       string            `json:"public_key"`
+	ValidPrincipals string            `json:"valid_principals,omitempty"`
+	CertType        string            `json:"cert_type,omitempty"`
+	KeyID           string            `json:"key_id,omitempty"`
+	TTL             string            `json:"ttl,omitempty"`
+	CriticalOptions map[string]string `json:"critical_options,omitempty"`
+	Extensions      map[string]string `json:"extensions,omitempty"`
+}
+
+// SSH returns a client for the SSH secrets engine mounted at mountPoint (default "ssh")
+func (c *Client) SSH(mountPoint string) *SSH {
+	if mountPoint == "" {
+		mountPoint = "ssh"
+	}
+	return &SSH{client: c, mountPoint: mountPoint}
+}
+
+// SignSSHKey signs the public key (authorized_keys format, ie the content of id_ed25519.pub)
+// with the role, returns the parsed certificate
+func (s *SSH) SignSSHKey(role string, publicKey []byte, opts *SSHSignOptions) (*SSHCertificate, error) {
+	var rsp struct {
+		SignedKey    string `json:"signed_key"`
+		SerialNumber string `json:"serial_number"`
+	}
+	payload := sshSignRequest{PublicKey: strings.TrimSpace(string(publicKey))}
+	if opts != nil {
+		payload.ValidPrincipals = strings.Join(opts.ValidPrincipals, ",")
+		payload.CertType = opts.CertType
+		payload.KeyID = opts.KeyID
+		payload.TTL = opts.TTL
+		payload.CriticalOptions = opts.CriticalOptions
+		payload.Extensions = opts.Extensions
+	}
+
+	if err := s.do("POST", "sign/"+role, payload, &rsp); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	cert, err := ParseSSHCertificate([]byte(rsp.SignedKey))
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	cert.SerialNumber = rsp.SerialNumber
+	return cert, nil
+}
+
+// GenerateOTP returns a one-time password for username on the host ip with the role,
+// username defaults to the role default user if empty
+func (s *SSH) GenerateOTP(role, ip, username string) (*SSHCredentials, error) {
+	var creds SSHCredentials
+	url := s.client.address.String() + "/v1/" + s.mountPoint + "/creds/" + role
+	payload := map[string]string{"ip": ip}
+	if username != "" {
+		payload["username"] = username
+	}
+
+	req, err := s.client.newRequest("POST", url)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if err = req.setJSONBody(payload); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	rsp, err := req.execute()
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if err = json.Unmarshal([]byte(rsp.Data), &creds); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	creds.Lease = leaseFromResponse(rsp)
+	return &creds, nil
+}
+
+// CAPublicKey returns the CA public key of the mount in authorized_keys format,
+// as expected in sshd TrustedUserCAKeys or known_hosts @cert-authority entries
+func (s *SSH) CAPublicKey() ([]byte, error) {
+	var rsp struct {
+		PublicKey string `json:"public_key"`
+	}
+	if err := s.do("GET", "config/ca", nil, &rsp); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if rsp.PublicKey == "" {
+		return nil, errors.New("No CA configured on mount " + s.mountPoint)
+	}
+	return []byte(strings.TrimSpace(rsp.PublicKey) + "\n"), nil
+}
+
+// do sends the request to the SSH path, parses the response data into out (if not nil)
+func (s *SSH) do(method, path string, payload, out interface{}) error {
+	return s.client.requestData(method, s.mountPoint+"/"+path, payload, out)
+}
+
+// IsValidAt returns true if the certificate is valid at the given time
+func (sc *SSHCertificate) IsValidAt(t time.Time) bool {
+	return !t.Before(sc.ValidAfter) && (sc.ValidBefore.IsZero() || t.Before(sc.ValidBefore))
+}
+
+// ParseSSHCertificate parses an OpenSSH certificate in authorized_keys format
+// (ie the content of an id_*-cert.pub file)
+func ParseSSHCertificate(signedKey []byte) (*SSHCertificate, error) {
+	key, _, _, _, err := ssh.ParseAuthorizedKey(signedKey)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	sshCert, ok := key.(*ssh.Certificate)
+	if !ok {
+		return nil, errors.New("Not an SSH certificate: " + key.Type())
+	}
+
+	cert := &SSHCertificate{
+		SignedKey:       ssh.MarshalAuthorizedKey(sshCert),
+		Type:            sshCert.Type(),
+		Serial:          sshCert.Serial,
+		KeyID:           sshCert.KeyId,
+		ValidPrincipals: sshCert.ValidPrincipals,
+		ValidAfter:      time.Unix(int64(sshCert.ValidAfter), 0),
+		CriticalOptions: sshCert.CriticalOptions,
+		Extensions:      sshCert.Extensions,
+	}
+	switch sshCert.CertType {
+	case ssh.UserCert:
+		cert.CertType = "user"
+	case ssh.HostCert:
+		cert.CertType = "host"
+	}
+	if sshCert.ValidBefore <= math.MaxInt64 {
+		cert.ValidBefore = time.Unix(int64(sshCert.ValidBefore), 0)
+	}
+	return cert, nil
+}
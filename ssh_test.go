@@ -0,0 +1,147 @@
+package vaultlib
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// newSSHPublicKey returns a new ed25519 public key in authorized_keys format
+func newSSHPublicKey(t *testing.T) []byte {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey() error = %v", err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("ssh.NewPublicKey() error = %v", err)
+	}
+	return ssh.MarshalAuthorizedKey(key)
+}
+
+func TestSSH_SignSSHKey(t *testing.T) {
+	conf := NewConfig()
+	conf.Token = "my-dev-root-vault-token"
+	vc, _ := NewClient(conf)
+	sshClient := vc.SSH("")
+
+	tests := []struct {
+		name    string
+		role    string
+		opts    *SSHSignOptions
+		wantErr bool
+	}{
+		{"principals", "my-ssh-role", &SSHSignOptions{ValidPrincipals: []string{"ubuntu", "admin"}, KeyID: "me", TTL: "10m"}, false},
+		{"defaults", "my-ssh-role", nil, false},
+		{"unknownRole", "not-a-role", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sshClient.SignSSHKey(tt.role, newSSHPublicKey(t), tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SSH.SignSSHKey() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Type != "ssh-ed25519-cert-v01@openssh.com" || got.CertType != "user" ||
+				got.SerialNumber == "" || !got.IsValidAt(time.Now()) {
+				t.Errorf("SSH.SignSSHKey() = %+v", got)
+			}
+			if tt.opts != nil && !reflect.DeepEqual(got.ValidPrincipals, tt.opts.ValidPrincipals) {
+				t.Errorf("SSH.SignSSHKey() principals = %v, want %v", got.ValidPrincipals, tt.opts.ValidPrincipals)
+			}
+		})
+	}
+}
+
+func TestSSH_GenerateOTP(t *testing.T) {
+	conf := NewConfig()
+	conf.Token = "my-dev-root-vault-token"
+	vc, _ := NewClient(conf)
+
+	got, err := vc.SSH("ssh").GenerateOTP("my-otp-role", "127.0.0.1", "")
+	if err != nil {
+		t.Fatalf("SSH.GenerateOTP() error = %v", err)
+	}
+	if got.Key == "" || got.KeyType != "otp" || got.Username != "vaultlib" || got.Lease.ID == "" {
+		t.Errorf("SSH.GenerateOTP() = %+v", got)
+	}
+	if _, err = vc.SSH("ssh").GenerateOTP("my-otp-role", "10.0.0.1", ""); err == nil {
+		t.Errorf("SSH.GenerateOTP() expected error for ip outside of role cidr_list")
+	}
+}
+
+func TestSSH_CAPublicKey(t *testing.T) {
+	conf := NewConfig()
+	conf.Token = "my-dev-root-vault-token"
+	vc, _ := NewClient(conf)
+
+	got, err := vc.SSH("").CAPublicKey()
+	if err != nil {
+		t.Fatalf("SSH.CAPublicKey() error = %v", err)
+	}
+	if !strings.HasPrefix(string(got), "ssh-rsa ") || !strings.HasSuffix(string(got), "\n") {
+		t.Errorf("SSH.CAPublicKey() = %s", got)
+	}
+}
+
+func TestParseSSHCertificate(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	_, caKey, _ := ed25519.GenerateKey(rand.Reader)
+	key, _ := ssh.NewPublicKey(pub)
+	signer, _ := ssh.NewSignerFromKey(caKey)
+	validAfter := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	sshCert := &ssh.Certificate{
+		Key:             key,
+		Serial:          42,
+		CertType:        ssh.UserCert,
+		KeyId:           "my-key-id",
+		ValidPrincipals: []string{"ubuntu", "admin"},
+		ValidAfter:      uint64(validAfter.Unix()),
+		ValidBefore:     ssh.CertTimeInfinity,
+		Permissions: ssh.Permissions{
+			CriticalOptions: map[string]string{"force-command": "/bin/true"},
+			Extensions:      map[string]string{"permit-pty": ""},
+		},
+	}
+	if err := sshCert.SignCert(rand.Reader, signer); err != nil {
+		t.Fatalf("ssh.Certificate.SignCert() error = %v", err)
+	}
+	signedKey := ssh.MarshalAuthorizedKey(sshCert)
+
+	got, err := ParseSSHCertificate(signedKey)
+	if err != nil {
+		t.Fatalf("ParseSSHCertificate() error = %v", err)
+	}
+	want := &SSHCertificate{
+		SignedKey:       signedKey,
+		Type:            ssh.CertAlgoED25519v01,
+		Serial:          42,
+		CertType:        "user",
+		KeyID:           "my-key-id",
+		ValidPrincipals: []string{"ubuntu", "admin"},
+		ValidAfter:      validAfter,
+		CriticalOptions: map[string]string{"force-command": "/bin/true"},
+		Extensions:      map[string]string{"permit-pty": ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSSHCertificate() = %+v, want %+v", got, want)
+	}
+	if !got.IsValidAt(time.Now()) || got.IsValidAt(validAfter.Add(-time.Second)) {
+		t.Errorf("SSHCertificate.IsValidAt() unexpected result")
+	}
+
+	for _, invalid := range []string{"", "ssh-ed25519 AAAA", string(newSSHPublicKey(t)), string(signedKey[:80])} {
+		if _, err = ParseSSHCertificate([]byte(invalid)); err == nil {
+			t.Errorf("ParseSSHCertificate(%q) expected error", invalid)
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package vaultlib
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FileOwner holds the owner of a rendered file
+type FileOwner struct {
+	UID int
+	GID int
+}
+
+// TemplateConfig holds the configuration of a template rendered to a file.
+//
+// Source is the template file path, Contents the template text (used if Source is empty).
+//
+// Mode is the rendered file mode (default 0600), Owner its owner (unchanged if nil).
+//
+// Command is run after each render changing the file (ie []string{"nginx", "-s", "reload"}).
+type TemplateConfig struct {
+	Source      string
+	Contents    string
+	Destination string
+	Mode        os.FileMode
+	Owner       *FileOwner
+	Command     []string
+}
+
+// TemplateRenderer renders text/template templates with secrets to files.
+//
+// Besides the text/template builtins, the templates can use the functions:
+//
+//	secret "path" "key"   the value of the secret key (read with GetSecret)
+//	secret "path"         the secret values, as a map[string]string
+//	pkiCert "pki/issue/my-role" "common_name=my.domain" ["alt_names=a,b" "ip_sans=..." "ttl=24h"]
+//	                      a *PKICertificate issued with the role, shared by the templates
+//	                      using the same arguments and re-issued after 2/3 of its lifetime
+//	base64 "value"        the base64 encoded value
+//	base64Decode "value"  the base64 decoded value
+type TemplateRenderer struct {
+	client    *Client
+	templates []*renderedTemplate
+	render    sync.Mutex
+	sync.Mutex
+	secrets  map[string]Secret
+	used     map[string]bool
+	certs    map[string]*PKICertificate
+	changed  chan struct{}
+	watching map[string]context.CancelFunc
+	// commands of the changed files not run successfully yet, guarded by render
+	pending [][]string
+}
+
+// renderedTemplate is a parsed template and its configuration
+type renderedTemplate struct {
+	config   TemplateConfig
+	template *template.Template
+}
+
+// NewTemplateRenderer returns a renderer for the templates, parsed on creation
+func (c *Client) NewTemplateRenderer(configs ...TemplateConfig) (*TemplateRenderer, error) {
+	r := &TemplateRenderer{
+		client:   c,
+		secrets:  make(map[string]Secret),
+		certs:    make(map[string]*PKICertificate),
+		changed:  make(chan struct{}, 1),
+		watching: make(map[string]context.CancelFunc),
+	}
+	funcs := template.FuncMap{
+		"secret":       r.secretFunc,
+		"pkiCert":      r.pkiCertFunc,
+		"base64":       base64Encode,
+		"base64Decode": base64Decode,
+	}
+
+	for _, config := range configs {
+		if config.Destination == "" {
+			return nil, errors.New("No destination provided for template " + config.Source)
+		}
+		if config.Mode == 0 {
+			config.Mode = 0600
+		}
+		text := config.Contents
+		if config.Source != "" {
+			content, err := ioutil.ReadFile(config.Source)
+			if err != nil {
+				return nil, errors.Wrap(errors.WithStack(err), errInfo())
+			}
+			text = string(content)
+		}
+		tmpl, err := template.New(config.Destination).Funcs(funcs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		r.templates = append(r.templates, &renderedTemplate{config: config, template: tmpl})
+	}
+	return r, nil
+}
+
+// Render renders the templates and writes the files whose content changed,
+// then runs the commands of the changed files (once per distinct command).
+//
+// A command that fails (or is not run because of an earlier error) is run again
+// by the next Render, even if its files did not change since.
+func (r *TemplateRenderer) Render() error {
+	r.render.Lock()
+	defer r.render.Unlock()
+
+	r.Lock()
+	r.used = make(map[string]bool)
+	r.Unlock()
+
+	for _, t := range r.templates {
+		var buf bytes.Buffer
+		if err := t.template.Execute(&buf, nil); err != nil {
+			return errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		changed, err := writeFileAtomic(t.config.Destination, buf.Bytes(), t.config.Mode, t.config.Owner)
+		if err != nil {
+			return errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		if changed && len(t.config.Command) > 0 {
+			r.addPending(t.config.Command)
+		}
+	}
+
+	for len(r.pending) > 0 {
+		command := r.pending[0]
+		cmd := exec.Command(command[0], command[1:]...)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return errors.Wrap(errors.WithStack(err), "Error running "+strings.Join(command, " "))
+		}
+		r.pending = r.pending[1:]
+	}
+	return nil
+}
+
+// addPending adds the command to the commands to run, if not already pending
+func (r *TemplateRenderer) addPending(command []string) {
+	for _, pending := range r.pending {
+		if strings.Join(pending, "\x00") == strings.Join(command, "\x00") {
+			return
+		}
+	}
+	r.pending = append(r.pending, command)
+}
+
+// Run renders the templates, then re-renders them each time a secret they use changes
+// (polled every interval with WatchSecret) or a certificate they use must be re-issued,
+// until ctx is done. Returns the first render error or the ctx error.
+//
+// The later render errors are reported in the client status (GetStatus), the render is retried after interval.
+func (r *TemplateRenderer) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("A positive interval must be specified")
+	}
+	if err := r.Render(); err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	var retry <-chan time.Time
+	for {
+		r.watch(ctx, interval)
+		renewal := time.NewTimer(time.Until(r.nextRenewal()))
+		select {
+		case <-ctx.Done():
+			renewal.Stop()
+			r.watch(ctx, interval)
+			return ctx.Err()
+		case <-r.changed:
+		case <-renewal.C:
+		case <-retry:
+		}
+		renewal.Stop()
+
+		retry = nil
+		if err := r.Render(); err != nil {
+			r.client.setStatus("Error rendering templates " + err.Error())
+			retry = time.After(interval)
+		}
+	}
+}
+
+// watch starts watching the secrets used by the last render, stops watching the others
+func (r *TemplateRenderer) watch(ctx context.Context, interval time.Duration) {
+	r.Lock()
+	defer r.Unlock()
+	for path, cancel := range r.watching {
+		if !r.used[path] || ctx.Err() != nil {
+			cancel()
+			delete(r.watching, path)
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	for path := range r.used {
+		if _, ok := r.watching[path]; ok {
+			continue
+		}
+		watchCtx, cancel := context.WithCancel(ctx)
+		r.watching[path] = cancel
+		go func(path string) {
+			_ = r.client.WatchSecret(watchCtx, path, interval, func(secret Secret) {
+				r.secretChanged(path, secret)
+			})
+		}(path)
+	}
+}
+
+// secretChanged stores the new secret and triggers a render if it differs from the rendered one
+func (r *TemplateRenderer) secretChanged(path string, secret Secret) {
+	r.Lock()
+	previous, ok := r.secrets[path]
+	r.secrets[path] = secret
+	r.Unlock()
+	if ok && secretHash(previous) == secretHash(secret) {
+		return
+	}
+	select {
+	case r.changed <- struct{}{}:
+	default:
+	}
+}
+
+// nextRenewal returns the time at which the first certificate must be re-issued
+func (r *TemplateRenderer) nextRenewal() time.Time {
+	r.Lock()
+	defer r.Unlock()
+	next := time.Now().Add(24 * time.Hour)
+	for _, cert := range r.certs {
+		if renewal := certRenewalTime(cert); renewal.Before(next) {
+			next = renewal
+		}
+	}
+	return next
+}
+
+// secretFunc is the "secret" template function
+func (r *TemplateRenderer) secretFunc(path string, key ...string) (interface{}, error) {
+	r.Lock()
+	secret, ok := r.secrets[path]
+	r.used[path] = true
+	r.Unlock()
+	if !ok {
+		var err error
+		if secret, err = r.client.GetSecret(path); err != nil {
+			return nil, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		r.Lock()
+		r.secrets[path] = secret
+		r.Unlock()
+	}
+
+	fields, err := secretFields(secret)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if len(key) == 0 {
+		return fields, nil
+	}
+	value, ok := fields[key[0]]
+	if !ok {
+		return nil, errors.New("Key " + key[0] + " not found in secret " + path)
+	}
+	return value, nil
+}
+
+// pkiCertFunc is the "pkiCert" template function
+func (r *TemplateRenderer) pkiCertFunc(path string, args ...string) (*PKICertificate, error) {
+	cacheKey := path + "\x00" + strings.Join(args, "\x00")
+	r.Lock()
+	cert, ok := r.certs[cacheKey]
+	r.Unlock()
+	if ok && time.Now().Before(certRenewalTime(cert)) {
+		return cert, nil
+	}
+
+	parts := strings.SplitN(path, "/issue/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, errors.New("Invalid PKI issue path " + path + ", expected <mount>/issue/<role>")
+	}
+	var certReq PKICertificateRequest
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 {
+			return nil, errors.New("Invalid pkiCert argument " + arg + ", expected key=value")
+		}
+		switch kv[0] {
+		case "common_name":
+			certReq.CommonName = kv[1]
+		case "alt_names":
+			certReq.AltNames = strings.Split(kv[1], ",")
+		case "ip_sans":
+			certReq.IPSANs = strings.Split(kv[1], ",")
+		case "uri_sans":
+			certReq.URISANs = strings.Split(kv[1], ",")
+		case "ttl":
+			certReq.TTL = kv[1]
+		case "exclude_cn_from_sans":
+			certReq.ExcludeCNFromSANs = kv[1] == "true"
+		default:
+			return nil, errors.New("Unsupported pkiCert argument " + kv[0])
+		}
+	}
+
+	cert, err := r.client.PKI(parts[0]).Issue(parts[1], &certReq)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	r.Lock()
+	r.certs[cacheKey] = cert
+	r.Unlock()
+	return cert, nil
+}
+
+// certRenewalTime returns the time at which 2/3 of the certificate lifetime has elapsed
+func certRenewalTime(cert *PKICertificate) time.Time {
+	leaf := cert.Certificate
+	return leaf.NotBefore.Add(leaf.NotAfter.Sub(leaf.NotBefore) * 2 / 3)
+}
+
+func base64Encode(value string) string {
+	return base64.StdEncoding.EncodeToString([]byte(value))
+}
+
+func base64Decode(value string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return string(decoded), nil
+}
+
+// writeFileAtomic writes the content to path through a temporary file renamed over it,
+// so that readers never see a partial file. Returns false if the file already had the content,
+// its mode and owner are then updated in place if they differ.
+func writeFileAtomic(path string, content []byte, mode os.FileMode, owner *FileOwner) (bool, error) {
+	if current, err := ioutil.ReadFile(path); err == nil && sha256.Sum256(current) == sha256.Sum256(content) {
+		if err = updateFileMode(path, mode, owner); err != nil {
+			return false, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		return false, nil
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return false, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(content); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), mode)
+	}
+	if err == nil && owner != nil {
+		err = os.Chown(tmp.Name(), owner.UID, owner.GID)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return false, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return true, nil
+}
+
+// updateFileMode sets the mode and owner (if not nil) of the file, when they differ
+func updateFileMode(path string, mode os.FileMode, owner *FileOwner) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if fi.Mode().Perm() != mode.Perm() {
+		if err = os.Chmod(path, mode); err != nil {
+			return err
+		}
+	}
+	if current, ok := fileOwner(fi); owner != nil && (!ok || current != *owner) {
+		return os.Chown(path, owner.UID, owner.GID)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package vaultlib
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateRenderer(t *testing.T) {
+	srv, vc := newTestVault(t)
+	srv.PutSecret("kv_v1/app", map[string]interface{}{"user": "app", "password": "s3cr3t0"})
+
+	dir, err := ioutil.TempDir("", "vaultlib-template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "app.conf")
+	marker := filepath.Join(dir, "reloads")
+
+	r, err := vc.NewTemplateRenderer(TemplateConfig{
+		Contents:    `user={{ secret "kv_v1/app" "user" }} password={{ secret "kv_v1/app" "password" | base64 }}{{ range $k, $v := secret "kv_v1/app" }} {{ $k }}{{ end }}`,
+		Destination: dest,
+		Mode:        0640,
+		Command:     []string{"sh", "-c", "echo reload >> " + marker},
+	})
+	if err != nil {
+		t.Fatalf("Client.NewTemplateRenderer() error = %v", err)
+	}
+
+	// the file is written and the command run only when the content changes
+	for i := 0; i < 2; i++ {
+		if err = r.Render(); err != nil {
+			t.Fatalf("TemplateRenderer.Render() error = %v", err)
+		}
+	}
+	content, _ := ioutil.ReadFile(dest)
+	if want := "user=app password=" + base64Encode("s3cr3t0") + " password user"; string(content) != want {
+		t.Errorf("TemplateRenderer.Render() content = %v, want %v", string(content), want)
+	}
+	if info, err := os.Stat(dest); err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("TemplateRenderer.Render() mode = %v, %v", info, err)
+	}
+	if reloads, _ := ioutil.ReadFile(marker); strings.Count(string(reloads), "reload") != 1 {
+		t.Errorf("TemplateRenderer.Render() reloads = %q, want 1", reloads)
+	}
+
+	// a mode change is applied to the unchanged file, the command is not run
+	config := r.templates[0].config
+	config.Mode = 0600
+	tightened, err := vc.NewTemplateRenderer(config)
+	if err != nil {
+		t.Fatalf("Client.NewTemplateRenderer() error = %v", err)
+	}
+	if err = tightened.Render(); err != nil {
+		t.Fatalf("TemplateRenderer.Render() error = %v", err)
+	}
+	if info, err := os.Stat(dest); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("TemplateRenderer.Render() mode change = %v, %v", info, err)
+	}
+	if reloads, _ := ioutil.ReadFile(marker); strings.Count(string(reloads), "reload") != 1 {
+		t.Errorf("TemplateRenderer.Render() reloads = %q, want 1", reloads)
+	}
+
+	// the file is re-rendered when the secret changes
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- r.Run(ctx, 10*time.Millisecond) }()
+	srv.PutSecret("kv_v1/app", map[string]interface{}{"user": "app", "password": "s3cr3t1"})
+	want := "user=app password=" + base64Encode("s3cr3t1") + " password user"
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if content, _ = ioutil.ReadFile(dest); string(content) == want {
+			break
+		}
+	}
+	if string(content) != want {
+		t.Errorf("TemplateRenderer.Run() content = %v, want %v", string(content), want)
+	}
+	cancel()
+	if err = <-done; err != context.Canceled {
+		t.Errorf("TemplateRenderer.Run() error = %v, want %v", err, context.Canceled)
+	}
+	if reloads, _ := ioutil.ReadFile(marker); strings.Count(string(reloads), "reload") != 2 {
+		t.Errorf("TemplateRenderer.Run() reloads = %q, want 2", reloads)
+	}
+}
+
+func TestTemplateRenderer_errors(t *testing.T) {
+	srv, vc := newTestVault(t)
+	srv.PutSecret("kv_v1/app", map[string]interface{}{"user": "app"})
+	dest := filepath.Join(os.TempDir(), "vaultlib-template-errors")
+	defer os.Remove(dest)
+
+	tests := []struct {
+		name     string
+		contents string
+	}{
+		{"missingKey", `{{ secret "kv_v1/app" "password" }}`},
+		{"invalidPKIPath", `{{ pkiCert "pki/my-role" "common_name=my.domain" }}`},
+		{"invalidPKIArgument", `{{ pkiCert "pki/issue/my-role" "common_name" }}`},
+		{"invalidBase64", `{{ base64Decode "%%" }}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := vc.NewTemplateRenderer(TemplateConfig{Contents: tt.contents, Destination: dest})
+			if err != nil {
+				t.Fatalf("Client.NewTemplateRenderer() error = %v", err)
+			}
+			if err = r.Render(); err == nil {
+				t.Errorf("TemplateRenderer.Render() expected error")
+			}
+		})
+	}
+
+	if _, err := vc.NewTemplateRenderer(TemplateConfig{Contents: `{{ secret `, Destination: dest}); err == nil {
+		t.Errorf("Client.NewTemplateRenderer() expected error for an invalid template")
+	}
+	if _, err := vc.NewTemplateRenderer(TemplateConfig{Contents: `text`}); err == nil {
+		t.Errorf("Client.NewTemplateRenderer() expected error for a missing destination")
+	}
+}
+
+func TestTemplateRenderer_commandRetry(t *testing.T) {
+	srv, vc := newTestVault(t)
+	srv.PutSecret("kv_v1/app", map[string]interface{}{"user": "app"})
+	dir, err := ioutil.TempDir("", "vaultlib-template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	failing := filepath.Join(dir, "failing")
+	marker := filepath.Join(dir, "reloads")
+	_ = ioutil.WriteFile(failing, nil, 0600)
+
+	r, err := vc.NewTemplateRenderer(TemplateConfig{
+		Contents:    `user={{ secret "kv_v1/app" "user" }}`,
+		Destination: filepath.Join(dir, "app.conf"),
+		Command:     []string{"sh", "-c", "test ! -e " + failing + " && echo reload >> " + marker},
+	})
+	if err != nil {
+		t.Fatalf("Client.NewTemplateRenderer() error = %v", err)
+	}
+	if err = r.Render(); err == nil {
+		t.Errorf("TemplateRenderer.Render() expected error for a failing command")
+	}
+
+	// the failed command is run again, though the file did not change
+	os.Remove(failing)
+	for i := 0; i < 2; i++ {
+		if err = r.Render(); err != nil {
+			t.Errorf("TemplateRenderer.Render() error = %v", err)
+		}
+	}
+	if reloads, _ := ioutil.ReadFile(marker); strings.Count(string(reloads), "reload") != 1 {
+		t.Errorf("TemplateRenderer.Render() reloads = %q, want 1", reloads)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+// +build !windows
+
+package vaultlib
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileOwner returns the owner of the file, false if not available
+func fileOwner(fi os.FileInfo) (FileOwner, bool) {
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return FileOwner{}, false
+	}
+	return FileOwner{UID: int(stat.Uid), GID: int(stat.Gid)}, true
+}
@@ -0,0 +1,8 @@
+package vaultlib
+
+import "os"
+
+// fileOwner returns the owner of the file, not available on Windows
+func fileOwner(fi os.FileInfo) (FileOwner, bool) {
+	return FileOwner{}, false
+}
@@ -27,6 +27,26 @@ sleep 5
 ./vault kv put kv_v2/path/json-secret @./test-files/secret.json >> /tmp/vaultdev.log
 ./vault kv put kv_v1/path/json-secret @./test-files/secret.json >> /tmp/vaultdev.log
 
+# create transit keys
+./vault secrets enable transit >> /tmp/vaultdev.log
+./vault write -f transit/keys/my-key >> /tmp/vaultdev.log
+./vault write transit/keys/my-derived-key derived=true >> /tmp/vaultdev.log
+./vault write transit/keys/my-ecdsa-key type=ecdsa-p256 >> /tmp/vaultdev.log
+./vault write transit/keys/my-rsa-key type=rsa-2048 >> /tmp/vaultdev.log
+
+# create PKI root CA and role
+./vault secrets enable pki >> /tmp/vaultdev.log
+./vault secrets tune -max-lease-ttl=87600h pki >> /tmp/vaultdev.log
+./vault write pki/root/generate/internal common_name=vaultlib.test ttl=87600h >> /tmp/vaultdev.log
+./vault write pki/roles/my-pki-role allowed_domains=vaultlib.test allow_subdomains=true allow_localhost=true max_ttl=72h >> /tmp/vaultdev.log
+./vault write pki/roles/my-ec-pki-role allowed_domains=vaultlib.test allow_subdomains=true key_type=ec key_bits=256 max_ttl=72h >> /tmp/vaultdev.log
+
+# create SSH CA and roles
+./vault secrets enable ssh >> /tmp/vaultdev.log
+./vault write ssh/config/ca generate_signing_key=true >> /tmp/vaultdev.log
+./vault write ssh/roles/my-ssh-role key_type=ca allow_user_certificates=true allowed_users="*" default_user=vaultlib ttl=30m >> /tmp/vaultdev.log
+./vault write ssh/roles/my-otp-role key_type=otp default_user=vaultlib cidr_list=127.0.0.0/8 >> /tmp/vaultdev.log
+
 # create policy
 ./vault policy write VaultDevAdmin test-files/VaultPolicy.hcl >> /tmp/vaultdev.log
 ./vault policy write VaultNoKV test-files/NoKVVaultPolicy.hcl >> /tmp/vaultdev.log
@@ -36,4 +56,7 @@ sleep 5
 ./vault write auth/approle/role/my-role policies=VaultDevAdmin token_num_uses=100 token_ttl=10s token_max_ttl=300m secret_id_num_uses=40 >> /tmp/vaultdev.log
 ./vault write auth/approle/role/no-kv policies=VaultNoKV token_num_uses=2 token_ttl=30m token_max_ttl=300m secret_id_num_uses=40 >> /tmp/vaultdev.log
 
+# create token role
+./vault write auth/token/roles/my-token-role allowed_policies=VaultDevAdmin period=1h >> /tmp/vaultdev.log
+
 unset VAULT_TOKEN
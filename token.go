@@ -0,0 +1,164 @@
+package vaultlib
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// TokenCreateRequest holds the parameters of a token creation.
+//
+// RoleName creates the token against the given token role (auth/token/create/:role_name).
+//
+// Orphan creates an orphan token (auth/token/create-orphan).
+type TokenCreateRequest struct {
+	RoleName        string            `json:"-"`
+	Orphan          bool              `json:"-"`
+	ID              string            `json:"id,omitempty"`
+	Policies        []string          `json:"policies,omitempty"`
+	Meta            map[string]string `json:"meta,omitempty"`
+	NoParent        bool              `json:"no_parent,omitempty"`
+	NoDefaultPolicy bool              `json:"no_default_policy,omitempty"`
+	Renewable       *bool             `json:"renewable,omitempty"`
+	TTL             string            `json:"ttl,omitempty"`
+	Type            string            `json:"type,omitempty"`
+	ExplicitMaxTTL  string            `json:"explicit_max_ttl,omitempty"`
+	DisplayName     string            `json:"display_name,omitempty"`
+	NumUses         int               `json:"num_uses,omitempty"`
+	Period          string            `json:"period,omitempty"`
+	EntityAlias     string            `json:"entity_alias,omitempty"`
+}
+
+// TokenAuth holds the Vault auth response returned on token creation,
+// the fields of vaultAuth (ClientToken, Accessor, Policies, Metadata, LeaseDuration,
+// Renewable and EntityID) plus the token creation ones
+type TokenAuth struct {
+	vaultAuth
+	TokenPolicies []string `json:"token_policies"`
+	TokenType     string   `json:"token_type"`
+	Orphan        bool     `json:"orphan"`
+}
+
+// CreateToken creates a new token (child of the client's token unless orphan).
+//
+// Returns the new token auth information.
+func (c *Client) CreateToken(tcr *TokenCreateRequest) (*TokenAuth, error) {
+	var tokenAuth TokenAuth
+	if tcr == nil {
+		tcr = new(TokenCreateRequest)
+	}
+
+	url := c.address.String() + "/v1/auth/token/create"
+	if tcr.RoleName != "" {
+		url = url + "/" + tcr.RoleName
+	} else if tcr.Orphan {
+		url = url + "-orphan"
+	}
+
+	req, err := c.newRequest("POST", url)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if err = req.setJSONBody(tcr); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	rsp, err := req.execute()
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	if err = json.Unmarshal([]byte(rsp.Auth), &tokenAuth); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return &tokenAuth, nil
+}
+
+// LookupToken returns the information of the given token
+func (c *Client) LookupToken(token string) (*VaultTokenInfo, error) {
+	return c.lookupToken("lookup", map[string]string{"token": token})
+}
+
+// LookupTokenAccessor returns the information of the token matching the given accessor.
+//
+// The token ID is not returned by Vault.
+func (c *Client) LookupTokenAccessor(accessor string) (*VaultTokenInfo, error) {
+	return c.lookupToken("lookup-accessor", map[string]string{"accessor": accessor})
+}
+
+func (c *Client) lookupToken(endpoint string, payload map[string]string) (*VaultTokenInfo, error) {
+	var tokenInfo VaultTokenInfo
+	url := c.address.String() + "/v1/auth/token/" + endpoint
+
+	req, err := c.newRequest("POST", url)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if err = req.setJSONBody(payload); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	rsp, err := req.execute()
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	if err = json.Unmarshal([]byte(rsp.Data), &tokenInfo); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return &tokenInfo, nil
+}
+
+// RevokeToken revokes the given token and all its children
+func (c *Client) RevokeToken(token string) error {
+	if err := c.revokeToken("revoke", map[string]string{"token": token}); err != nil {
+		return err
+	}
+	if token == c.getTokenID() {
+		c.invalidateTokenSecrets()
+	}
+	return nil
+}
+
+// RevokeTokenAccessor revokes the token matching the given accessor and all its children
+func (c *Client) RevokeTokenAccessor(accessor string) error {
+	if err := c.revokeToken("revoke-accessor", map[string]string{"accessor": accessor}); err != nil {
+		return err
+	}
+	if accessor == c.GetTokenInfo().Accessor {
+		c.invalidateTokenSecrets()
+	}
+	return nil
+}
+
+// RevokeSelf revokes the client's token and all its children.
+//
+// The client is no longer authenticated afterwards.
+func (c *Client) RevokeSelf() error {
+	if err := c.revokeToken("revoke-self", map[string]string{}); err != nil {
+		return err
+	}
+	c.withLockContext(func() {
+		c.isAuthenticated = false
+		c.status = "Token revoked"
+	})
+	c.invalidateTokenSecrets()
+	return nil
+}
+
+func (c *Client) revokeToken(endpoint string, payload map[string]string) error {
+	url := c.address.String() + "/v1/auth/token/" + endpoint
+
+	req, err := c.newRequest("POST", url)
+	if err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if err = req.setJSONBody(payload); err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	if _, err = req.execute(); err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package vaultlib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClient_CreateToken(t *testing.T) {
+	conf := NewConfig()
+	conf.Token = "my-dev-root-vault-token"
+	vc, _ := NewClient(conf)
+	notRenewable := false
+
+	tests := []struct {
+		name       string
+		tcr        *TokenCreateRequest
+		wantPolicy []string
+		wantOrphan bool
+		wantErr    bool
+	}{
+		{"child", &TokenCreateRequest{Policies: []string{"VaultDevAdmin"}, TTL: "1h", NumUses: 10}, []string{"VaultDevAdmin", "default"}, false, false},
+		{"orphan", &TokenCreateRequest{Orphan: true, NoDefaultPolicy: true, Policies: []string{"VaultDevAdmin"}, Renewable: &notRenewable}, []string{"VaultDevAdmin"}, true, false},
+		{"role", &TokenCreateRequest{RoleName: "my-token-role", Period: "1h"}, []string{"VaultDevAdmin", "default"}, false, false},
+		{"badRole", &TokenCreateRequest{RoleName: "not-exist"}, nil, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := vc.CreateToken(tt.tcr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Client.CreateToken() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got.Policies, tt.wantPolicy) || got.Orphan != tt.wantOrphan {
+				t.Errorf("Client.CreateToken() = %v, want policies %v orphan %v", got, tt.wantPolicy, tt.wantOrphan)
+			}
+		})
+	}
+}
+
+func TestClient_LookupAndRevokeToken(t *testing.T) {
+	conf := NewConfig()
+	conf.Token = "my-dev-root-vault-token"
+	vc, _ := NewClient(conf)
+
+	first, err := vc.CreateToken(&TokenCreateRequest{Policies: []string{"VaultDevAdmin"}})
+	if err != nil {
+		t.Fatalf("Client.CreateToken() error = %v", err)
+	}
+	second, err := vc.CreateToken(&TokenCreateRequest{Policies: []string{"VaultDevAdmin"}})
+	if err != nil {
+		t.Fatalf("Client.CreateToken() error = %v", err)
+	}
+
+	info, err := vc.LookupToken(first.ClientToken)
+	if err != nil || info.Accessor != first.Accessor {
+		t.Errorf("Client.LookupToken() = %v, error = %v, want accessor %v", info, err, first.Accessor)
+	}
+	info, err = vc.LookupTokenAccessor(second.Accessor)
+	if err != nil || info.Accessor != second.Accessor {
+		t.Errorf("Client.LookupTokenAccessor() = %v, error = %v, want accessor %v", info, err, second.Accessor)
+	}
+
+	if err = vc.RevokeToken(first.ClientToken); err != nil {
+		t.Errorf("Client.RevokeToken() error = %v", err)
+	}
+	if _, err = vc.LookupToken(first.ClientToken); err == nil {
+		t.Errorf("Client.LookupToken() on revoked token should fail")
+	}
+	if err = vc.RevokeTokenAccessor(second.Accessor); err != nil {
+		t.Errorf("Client.RevokeTokenAccessor() error = %v", err)
+	}
+	if _, err = vc.LookupTokenAccessor(second.Accessor); err == nil {
+		t.Errorf("Client.LookupTokenAccessor() on revoked token should fail")
+	}
+
+	third, _ := vc.CreateToken(&TokenCreateRequest{Policies: []string{"VaultDevAdmin"}})
+	conf.Token = third.ClientToken
+	childCli, _ := NewClient(conf)
+	if err = childCli.RevokeSelf(); err != nil {
+		t.Errorf("Client.RevokeSelf() error = %v", err)
+	}
+	if childCli.IsAuthenticated() {
+		t.Errorf("Client.IsAuthenticated() after RevokeSelf should be false")
+	}
+}
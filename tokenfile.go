@@ -0,0 +1,153 @@
+package vaultlib
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// tokenFileWatchInterval is the delay between two checks of the token file
+var tokenFileWatchInterval = 5 * time.Second
+
+// tokenFile holds the state of the file the client token is read from
+// (ie Vault Agent auto-auth file sink or ~/.vault-token)
+type tokenFile struct {
+	sync.Mutex
+	path     string
+	modTime  time.Time
+	size     int64
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// defaultTokenFile returns the path of the Vault CLI token helper file
+// (~/.vault-token) if it exists, an empty string otherwise
+func defaultTokenFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	path := filepath.Join(home, ".vault-token")
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// readTokenFile returns the token contained in the file
+func readTokenFile(path string) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return "", errors.New("Token file " + path + " is empty")
+	}
+	return token, nil
+}
+
+// setTokenFromFile launches the go routine watching the file for changes,
+// then reads the token from the file and set it in the client.
+// The file is watched even if the first read fails, the token may be written later (ie by Vault Agent),
+// the caller stops the watch if it gives up the client (see NewClient).
+func (c *Client) setTokenFromFile(path string) error {
+	c.tokenFile = &tokenFile{path: path, stop: make(chan struct{})}
+	go c.watchTokenFile(c.tokenFile, tokenFileWatchInterval)
+	if _, err := c.reloadTokenFile(); err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return nil
+}
+
+// StopTokenFileWatch stops watching the token file (see Config.TokenFile),
+// the token is still reloaded from the file on a permission denied response.
+// Does nothing if the token is not read from a file.
+func (c *Client) StopTokenFileWatch() {
+	if c.tokenFile == nil {
+		return
+	}
+	c.tokenFile.stopOnce.Do(func() {
+		close(c.tokenFile.stop)
+	})
+}
+
+// reloadTokenFile reads the token file and swaps the client's token if it changed.
+// Returns true if the token has been swapped.
+func (c *Client) reloadTokenFile() (bool, error) {
+	if c.tokenFile == nil {
+		return false, nil
+	}
+	swapped, err := c.swapTokenFromFile()
+	if err != nil || !swapped {
+		return false, err
+	}
+	// lookup is done outside of the file lock as it may trigger a reload on 403
+	if err := c.setTokenInfo(); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// swapTokenFromFile replaces the client's token with the file content if they differ
+func (c *Client) swapTokenFromFile() (bool, error) {
+	c.tokenFile.Lock()
+	defer c.tokenFile.Unlock()
+
+	if fi, err := os.Stat(c.tokenFile.path); err == nil {
+		c.tokenFile.modTime = fi.ModTime()
+		c.tokenFile.size = fi.Size()
+	}
+
+	token, err := readTokenFile(c.tokenFile.path)
+	if err != nil {
+		return false, err
+	}
+	if token == c.getTokenID() {
+		return false, nil
+	}
+
+	c.withLockContext(func() {
+		c.token = &VaultTokenInfo{ID: token}
+	})
+	c.invalidateTokenSecrets()
+	return true, nil
+}
+
+// tokenFileChanged returns true if the token file has been modified since last read
+func (c *Client) tokenFileChanged() bool {
+	c.tokenFile.Lock()
+	defer c.tokenFile.Unlock()
+	fi, err := os.Stat(c.tokenFile.path)
+	if err != nil {
+		return false
+	}
+	return !fi.ModTime().Equal(c.tokenFile.modTime) || fi.Size() != c.tokenFile.size
+}
+
+// watch the token file for changes, launched at client creation time as a go routine
+// and stopped by StopTokenFileWatch
+func (c *Client) watchTokenFile(tf *tokenFile, interval time.Duration) {
+	for {
+		timer := time.NewTimer(interval)
+		select {
+		case <-tf.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if !c.tokenFileChanged() {
+			continue
+		}
+		if _, err := c.reloadTokenFile(); err != nil {
+			c.setStatus("Error reloading token file " + err.Error())
+			continue
+		}
+		c.setStatus("token reloaded from file")
+	}
+}
@@ -0,0 +1,108 @@
+package vaultlib
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mch1307/vaultlib/vaulttest"
+	"github.com/pkg/errors"
+)
+
+func Test_readTokenFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vaultlib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	okFile := filepath.Join(dir, "token")
+	_ = ioutil.WriteFile(okFile, []byte("my-dev-root-vault-token\n"), 0600)
+	emptyFile := filepath.Join(dir, "empty")
+	_ = ioutil.WriteFile(emptyFile, []byte("  \n"), 0600)
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"ok", okFile, "my-dev-root-vault-token", false},
+		{"empty", emptyFile, "", true},
+		{"notExist", filepath.Join(dir, "notExist"), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readTokenFile(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("readTokenFile() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("readTokenFile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_tokenFileReload(t *testing.T) {
+	srv := vaulttest.NewServer()
+	defer srv.Close()
+	dir, err := ioutil.TempDir("", "vaultlib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tokenPath := filepath.Join(dir, "token")
+	_ = ioutil.WriteFile(tokenPath, []byte("bad-token"), 0600)
+
+	defer func(interval time.Duration) { tokenFileWatchInterval = interval }(tokenFileWatchInterval)
+	tokenFileWatchInterval = 50 * time.Millisecond
+	conf := NewConfig()
+	conf.Address = srv.URL
+	conf.Token = ""
+	conf.TokenFile = tokenPath
+	vc, err := NewClient(conf)
+	if err == nil {
+		t.Fatalf("NewClient() with bad token file should fail")
+	}
+
+	// the file did not change, the 403 is returned
+	_, err = vc.RawRequest("GET", "/v1/auth/token/lookup-self", nil)
+	if rspErr, ok := errors.Cause(err).(*ResponseError); !ok || rspErr.StatusCode != http.StatusForbidden {
+		t.Errorf("Client.RawRequest() error = %v, want a 403", err)
+	}
+
+	// Vault Agent writes a new token to the sink, the watch is stopped by NewClient on error:
+	// the token is reloaded on 403 and the request retried
+	_ = ioutil.WriteFile(tokenPath, []byte(vaulttest.RootToken), 0600)
+	time.Sleep(2 * tokenFileWatchInterval)
+	if got := vc.getTokenID(); got != "bad-token" {
+		t.Errorf("Client token = %v, want the token not reloaded by a watcher", got)
+	}
+	if _, err = vc.RawRequest("GET", "/v1/auth/token/lookup-self", nil); err != nil {
+		t.Errorf("Client.RawRequest() error = %v, token should have been reloaded on 403", err)
+	}
+	if got := vc.GetTokenInfo().ID; got != vaulttest.RootToken {
+		t.Errorf("Client.GetTokenInfo().ID = %v, want %v", got, vaulttest.RootToken)
+	}
+
+	// the watcher of a client created successfully picks up the new tokens
+	vc, err = NewClient(conf)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	defer vc.StopTokenFileWatch()
+	token := srv.CreateToken(0)
+	_ = ioutil.WriteFile(tokenPath, []byte(token), 0600)
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if vc.GetTokenInfo().ID == token {
+			break
+		}
+	}
+	if got := vc.GetTokenInfo().ID; got != token {
+		t.Errorf("Client.GetTokenInfo().ID = %v, want the token reloaded by the watcher", got)
+	}
+}
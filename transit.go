@@ -0,0 +1,233 @@
+package vaultlib
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Transit holds the Transit secrets engine client
+type Transit struct {
+	client     *Client
+	mountPoint string
+}
+
+// TransitOptions holds the optional parameters of the Transit operations.
+//
+// KeyVersion is the key version to encrypt or rewrap with (latest if 0).
+//
+// Context is the key derivation context, required for derived keys.
+//
+// Nonce is the nonce used for convergent encryption.
+//
+// AssociatedData is the additional authenticated data of AEAD keys.
+type TransitOptions struct {
+	KeyVersion     int
+	Context        []byte
+	Nonce          []byte
+	AssociatedData []byte
+}
+
+// TransitBatchItem holds one item of a Transit batch operation.
+//
+// Plaintext is used by EncryptBatch, Ciphertext by DecryptBatch and RewrapBatch.
+//
+// KeyVersion is the key version to encrypt or rewrap the item with, opts.KeyVersion if 0.
+// Context, Nonce and AssociatedData default to the opts ones if empty.
+type TransitBatchItem struct {
+	Plaintext      []byte `json:"plaintext"`
+	Ciphertext     string `json:"ciphertext,omitempty"`
+	Context        []byte `json:"context,omitempty"`
+	Nonce          []byte `json:"nonce,omitempty"`
+	AssociatedData []byte `json:"associated_data,omitempty"`
+	KeyVersion     int    `json:"key_version,omitempty"`
+}
+
+// TransitBatchResult holds the result of one item of a Transit batch operation.
+//
+// Err is set if Vault failed to process the item.
+type TransitBatchResult struct {
+	Plaintext  []byte
+	Ciphertext string
+	KeyVersion int
+	Err        error
+}
+
+// transitRequest holds the Transit request payload. []byte are base64 encoded by json.
+type transitRequest struct {
+	Ciphertext     string             `json:"ciphertext,omitempty"`
+	Context        []byte             `json:"context,omitempty"`
+	Nonce          []byte             `json:"nonce,omitempty"`
+	AssociatedData []byte             `json:"associated_data,omitempty"`
+	KeyVersion     int                `json:"key_version,omitempty"`
+	BatchInput     []TransitBatchItem `json:"batch_input,omitempty"`
+
+	Input               []byte `json:"input,omitempty"`
+	Signature           string `json:"signature,omitempty"`
+	HMAC                string `json:"hmac,omitempty"`
+	HashAlgorithm       string `json:"hash_algorithm,omitempty"`
+	Algorithm           string `json:"algorithm,omitempty"`
+	SignatureAlgorithm  string `json:"signature_algorithm,omitempty"`
+	MarshalingAlgorithm string `json:"marshaling_algorithm,omitempty"`
+	Prehashed           bool   `json:"prehashed,omitempty"`
+	Bits                int    `json:"bits,omitempty"`
+}
+
+// transitEncryptRequest holds the encrypt request payload, the plaintext is
+// sent even if empty as Vault requires it
+type transitEncryptRequest struct {
+	*transitRequest
+	Plaintext []byte `json:"plaintext"`
+}
+
+// transitResponse holds the Transit response data
+type transitResponse struct {
+	Plaintext    []byte            `json:"plaintext"`
+	Ciphertext   string            `json:"ciphertext"`
+	KeyVersion   int               `json:"key_version"`
+	Error        string            `json:"error"`
+	BatchResults []transitResponse `json:"batch_results"`
+	Signature    string            `json:"signature"`
+	HMAC         string            `json:"hmac"`
+	Valid        bool              `json:"valid"`
+}
+
+// Transit returns a client for the Transit secrets engine mounted at mountPoint (default "transit")
+func (c *Client) Transit(mountPoint string) *Transit {
+	if mountPoint == "" {
+		mountPoint = "transit"
+	}
+	return &Transit{client: c, mountPoint: mountPoint}
+}
+
+// Encrypt encrypts the plaintext with the named key.
+//
+// Returns the Vault ciphertext (ie "vault:v1:...").
+func (t *Transit) Encrypt(key string, plaintext []byte, opts *TransitOptions) (string, error) {
+	payload := transitEncryptRequest{transitRequest: newTransitRequest(opts), Plaintext: plaintext}
+	if payload.Plaintext == nil {
+		payload.Plaintext = []byte{}
+	}
+
+	var rsp transitResponse
+	if err := t.do("POST", "encrypt/"+key, payload, &rsp); err != nil {
+		return "", errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return rsp.Ciphertext, nil
+}
+
+// Decrypt decrypts the ciphertext with the named key.
+//
+// Returns the plaintext.
+func (t *Transit) Decrypt(key, ciphertext string, opts *TransitOptions) ([]byte, error) {
+	payload := newTransitRequest(opts)
+	payload.KeyVersion = 0
+	payload.Ciphertext = ciphertext
+
+	var rsp transitResponse
+	if err := t.do("POST", "decrypt/"+key, payload, &rsp); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return rsp.Plaintext, nil
+}
+
+// Rewrap re-encrypts the ciphertext with the latest (or opts.KeyVersion) version of the named key,
+// without exposing the plaintext.
+//
+// Returns the new ciphertext.
+func (t *Transit) Rewrap(key, ciphertext string, opts *TransitOptions) (string, error) {
+	payload := newTransitRequest(opts)
+	payload.Ciphertext = ciphertext
+
+	var rsp transitResponse
+	if err := t.do("POST", "rewrap/"+key, payload, &rsp); err != nil {
+		return "", errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return rsp.Ciphertext, nil
+}
+
+// EncryptBatch encrypts the items Plaintext with the named key.
+//
+// Returns one result per item, in the same order, with per item error.
+func (t *Transit) EncryptBatch(key string, items []TransitBatchItem, opts *TransitOptions) ([]TransitBatchResult, error) {
+	return t.batch("encrypt/"+key, items, opts)
+}
+
+// DecryptBatch decrypts the items Ciphertext with the named key.
+//
+// Returns one result per item, in the same order, with per item error.
+func (t *Transit) DecryptBatch(key string, items []TransitBatchItem, opts *TransitOptions) ([]TransitBatchResult, error) {
+	return t.batch("decrypt/"+key, items, opts)
+}
+
+// RewrapBatch re-encrypts the items Ciphertext with the latest (or opts.KeyVersion) version of the named key.
+//
+// Returns one result per item, in the same order, with per item error.
+func (t *Transit) RewrapBatch(key string, items []TransitBatchItem, opts *TransitOptions) ([]TransitBatchResult, error) {
+	return t.batch("rewrap/"+key, items, opts)
+}
+
+func (t *Transit) batch(path string, items []TransitBatchItem, opts *TransitOptions) ([]TransitBatchResult, error) {
+	if len(items) == 0 {
+		return nil, errors.New("No batch item provided")
+	}
+	payload := new(transitRequest)
+	payload.BatchInput = items
+	// the options are set per item, Vault ignores them at the batch level
+	if opts != nil {
+		payload.BatchInput = make([]TransitBatchItem, len(items))
+		for i, item := range items {
+			if item.KeyVersion == 0 {
+				item.KeyVersion = opts.KeyVersion
+			}
+			if len(item.Context) == 0 {
+				item.Context = opts.Context
+			}
+			if len(item.Nonce) == 0 {
+				item.Nonce = opts.Nonce
+			}
+			if len(item.AssociatedData) == 0 {
+				item.AssociatedData = opts.AssociatedData
+			}
+			payload.BatchInput[i] = item
+		}
+	}
+
+	var rsp transitResponse
+	err := t.do("POST", path, payload, &rsp)
+	// Vault returns an error status when an item fails, along with the batch results
+	if len(rsp.BatchResults) != len(items) {
+		if err == nil {
+			err = errors.New("Vault returned an unexpected number of batch results")
+		}
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	results := make([]TransitBatchResult, len(items))
+	for i, r := range rsp.BatchResults {
+		results[i] = TransitBatchResult{
+			Plaintext:  r.Plaintext,
+			Ciphertext: r.Ciphertext,
+			KeyVersion: r.KeyVersion,
+		}
+		if r.Error != "" {
+			results[i].Err = errors.New(r.Error)
+		}
+	}
+	return results, nil
+}
+
+// do sends the request to the Transit path, parses the response data into out (if not nil)
+func (t *Transit) do(method, path string, payload, out interface{}) error {
+	return t.client.requestData(method, t.mountPoint+"/"+path, payload, out)
+}
+
+// newTransitRequest returns a request payload initialized with the options
+func newTransitRequest(opts *TransitOptions) *transitRequest {
+	payload := new(transitRequest)
+	if opts != nil {
+		payload.KeyVersion = opts.KeyVersion
+		payload.Context = opts.Context
+		payload.Nonce = opts.Nonce
+		payload.AssociatedData = opts.AssociatedData
+	}
+	return payload
+}
@@ -0,0 +1,119 @@
+package vaultlib
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// TransitDataKey holds a data key generated by Vault.
+//
+// Plaintext is the data key, empty for wrapped data keys.
+//
+// Ciphertext is the data key encrypted with the Transit key.
+type TransitDataKey struct {
+	Plaintext  []byte
+	Ciphertext string
+}
+
+// Envelope holds a payload encrypted locally (AES-GCM) with a data key
+// generated by Vault. Only the wrapped data key is stored.
+type Envelope struct {
+	WrappedKey string `json:"wrapped_key"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// GenerateDataKey generates a new data key (bits 128, 256 or 512, default 256)
+// and returns it both in plaintext and encrypted with the named key.
+func (t *Transit) GenerateDataKey(key string, bits int, opts *TransitOptions) (*TransitDataKey, error) {
+	return t.generateDataKey("plaintext", key, bits, opts)
+}
+
+// GenerateWrappedDataKey generates a new data key (bits 128, 256 or 512, default 256)
+// and returns it encrypted with the named key only.
+func (t *Transit) GenerateWrappedDataKey(key string, bits int, opts *TransitOptions) (*TransitDataKey, error) {
+	return t.generateDataKey("wrapped", key, bits, opts)
+}
+
+func (t *Transit) generateDataKey(keyType, key string, bits int, opts *TransitOptions) (*TransitDataKey, error) {
+	var rsp transitResponse
+	payload := newTransitRequest(opts)
+	payload.KeyVersion = 0
+	payload.Bits = bits
+
+	if err := t.do("POST", "datakey/"+keyType+"/"+key, payload, &rsp); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return &TransitDataKey{Plaintext: rsp.Plaintext, Ciphertext: rsp.Ciphertext}, nil
+}
+
+// EnvelopeEncrypt encrypts the plaintext locally with AES-GCM using a new 256 bits
+// data key generated from the named key. The plaintext data key is discarded.
+func (t *Transit) EnvelopeEncrypt(key string, plaintext []byte, opts *TransitOptions) (*Envelope, error) {
+	dataKey, err := t.GenerateDataKey(key, 256, opts)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	defer zero(dataKey.Plaintext)
+
+	gcm, err := newGCM(dataKey.Plaintext)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	env := &Envelope{WrappedKey: dataKey.Ciphertext}
+	env.Nonce = make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, env.Nonce); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	// the wrapped key is authenticated along with the ciphertext
+	env.Ciphertext = gcm.Seal(nil, env.Nonce, plaintext, []byte(env.WrappedKey))
+	return env, nil
+}
+
+// EnvelopeDecrypt decrypts the envelope data key with the named key,
+// then decrypts and authenticates the payload locally.
+func (t *Transit) EnvelopeDecrypt(key string, env *Envelope, opts *TransitOptions) ([]byte, error) {
+	if env == nil {
+		return nil, errors.New("No envelope provided")
+	}
+	dataKey, err := t.Decrypt(key, env.WrappedKey, opts)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	defer zero(dataKey)
+
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if len(env.Nonce) != gcm.NonceSize() {
+		return nil, errors.New("Invalid envelope nonce size")
+	}
+
+	plaintext, err := gcm.Open(nil, env.Nonce, env.Ciphertext, []byte(env.WrappedKey))
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return plaintext, nil
+}
+
+// newGCM returns an AES-GCM cipher for the key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// zero overwrites the key material
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,52 @@
+package vaultlib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTransit_GenerateDataKey(t *testing.T) {
+	conf := NewConfig()
+	conf.Token = "my-dev-root-vault-token"
+	vc, _ := NewClient(conf)
+	transit := vc.Transit("")
+
+	dataKey, err := transit.GenerateDataKey("my-key", 512, nil)
+	if err != nil || len(dataKey.Plaintext) != 64 || dataKey.Ciphertext == "" {
+		t.Errorf("Transit.GenerateDataKey() = %v, error = %v", dataKey, err)
+	}
+	decrypted, err := transit.Decrypt("my-key", dataKey.Ciphertext, nil)
+	if err != nil || !reflect.DeepEqual(decrypted, dataKey.Plaintext) {
+		t.Errorf("Transit.Decrypt() data key = %v, error = %v", decrypted, err)
+	}
+
+	wrapped, err := transit.GenerateWrappedDataKey("my-derived-key", 0, &TransitOptions{Context: []byte("ctx")})
+	if err != nil || len(wrapped.Plaintext) != 0 || wrapped.Ciphertext == "" {
+		t.Errorf("Transit.GenerateWrappedDataKey() = %v, error = %v", wrapped, err)
+	}
+}
+
+func TestTransit_Envelope(t *testing.T) {
+	conf := NewConfig()
+	conf.Token = "my-dev-root-vault-token"
+	vc, _ := NewClient(conf)
+	transit := vc.Transit("")
+	payload := make([]byte, 1<<20)
+
+	env, err := transit.EnvelopeEncrypt("my-key", payload, nil)
+	if err != nil {
+		t.Fatalf("Transit.EnvelopeEncrypt() error = %v", err)
+	}
+	got, err := transit.EnvelopeDecrypt("my-key", env, nil)
+	if err != nil || !reflect.DeepEqual(got, payload) {
+		t.Errorf("Transit.EnvelopeDecrypt() error = %v", err)
+	}
+
+	env.Ciphertext[0] ^= 0xff
+	if _, err = transit.EnvelopeDecrypt("my-key", env, nil); err == nil {
+		t.Errorf("Transit.EnvelopeDecrypt() on tampered envelope should fail")
+	}
+	if _, err = transit.EnvelopeDecrypt("my-key", nil, nil); err == nil {
+		t.Errorf("Transit.EnvelopeDecrypt() without envelope should fail")
+	}
+}
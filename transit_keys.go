@@ -0,0 +1,188 @@
+package vaultlib
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TransitKeyCreateOptions holds the parameters of a Transit key creation.
+//
+// Type is the key type (ie "aes256-gcm96", "chacha20-poly1305", "ed25519", "ecdsa-p256", "rsa-2048"),
+// Vault default ("aes256-gcm96") if empty.
+type TransitKeyCreateOptions struct {
+	Type                 string `json:"type,omitempty"`
+	Derived              bool   `json:"derived,omitempty"`
+	ConvergentEncryption bool   `json:"convergent_encryption,omitempty"`
+	Exportable           bool   `json:"exportable,omitempty"`
+	AllowPlaintextBackup bool   `json:"allow_plaintext_backup,omitempty"`
+}
+
+// TransitKeyConfig holds the Transit key configuration to update. Nil fields are left unchanged.
+type TransitKeyConfig struct {
+	MinDecryptionVersion *int  `json:"min_decryption_version,omitempty"`
+	MinEncryptionVersion *int  `json:"min_encryption_version,omitempty"`
+	DeletionAllowed      *bool `json:"deletion_allowed,omitempty"`
+	Exportable           *bool `json:"exportable,omitempty"`
+	AllowPlaintextBackup *bool `json:"allow_plaintext_backup,omitempty"`
+}
+
+// TransitKeyVersion holds the information of a Transit key version.
+//
+// PublicKey is only set for asymmetric keys.
+type TransitKeyVersion struct {
+	CreationTime time.Time `json:"creation_time"`
+	PublicKey    string    `json:"public_key"`
+	Name         string    `json:"name"`
+}
+
+// TransitKey holds the Transit key information
+type TransitKey struct {
+	Name                 string                    `json:"name"`
+	Type                 string                    `json:"type"`
+	Derived              bool                      `json:"derived"`
+	ConvergentEncryption bool                      `json:"convergent_encryption"`
+	Exportable           bool                      `json:"exportable"`
+	AllowPlaintextBackup bool                      `json:"allow_plaintext_backup"`
+	DeletionAllowed      bool                      `json:"deletion_allowed"`
+	LatestVersion        int                       `json:"latest_version"`
+	MinAvailableVersion  int                       `json:"min_available_version"`
+	MinDecryptionVersion int                       `json:"min_decryption_version"`
+	MinEncryptionVersion int                       `json:"min_encryption_version"`
+	SupportsEncryption   bool                      `json:"supports_encryption"`
+	SupportsDecryption   bool                      `json:"supports_decryption"`
+	SupportsDerivation   bool                      `json:"supports_derivation"`
+	SupportsSigning      bool                      `json:"supports_signing"`
+	Versions             map[int]TransitKeyVersion `json:"-"`
+}
+
+// VersionNumbers returns the available key versions, sorted
+func (k *TransitKey) VersionNumbers() []int {
+	versions := make([]int, 0, len(k.Versions))
+	for v := range k.Versions {
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+// CreateKey creates a new named key
+func (t *Transit) CreateKey(name string, opts *TransitKeyCreateOptions) error {
+	if opts == nil {
+		opts = new(TransitKeyCreateOptions)
+	}
+	if err := t.do("POST", "keys/"+name, opts, nil); err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return nil
+}
+
+// ReadKey returns the named key information
+func (t *Transit) ReadKey(name string) (*TransitKey, error) {
+	var rsp struct {
+		TransitKey
+		Keys map[string]json.RawMessage `json:"keys"`
+	}
+	if err := t.do("GET", "keys/"+name, nil, &rsp); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	// symmetric keys versions hold the creation unix time, asymmetric keys an object
+	key := rsp.TransitKey
+	key.Versions = make(map[int]TransitKeyVersion, len(rsp.Keys))
+	for v, raw := range rsp.Keys {
+		var keyVersion TransitKeyVersion
+		version, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		var creationTime int64
+		if err = json.Unmarshal(raw, &creationTime); err == nil {
+			keyVersion.CreationTime = time.Unix(creationTime, 0)
+		} else if err = json.Unmarshal(raw, &keyVersion); err != nil {
+			return nil, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		key.Versions[version] = keyVersion
+	}
+	return &key, nil
+}
+
+// ListKeys returns the names of the keys
+func (t *Transit) ListKeys() ([]string, error) {
+	var list struct {
+		Keys []string `json:"keys"`
+	}
+	if err := t.do("LIST", "keys", nil, &list); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return list.Keys, nil
+}
+
+// RotateKey creates a new version of the named key, used for new encryptions
+func (t *Transit) RotateKey(name string) error {
+	if err := t.do("POST", "keys/"+name+"/rotate", nil, nil); err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return nil
+}
+
+// UpdateKeyConfig updates the named key configuration
+func (t *Transit) UpdateKeyConfig(name string, config *TransitKeyConfig) error {
+	if config == nil {
+		return errors.New("No key config provided")
+	}
+	if err := t.do("POST", "keys/"+name+"/config", config, nil); err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return nil
+}
+
+// TrimKey permanently deletes the named key versions older than minAvailableVersion.
+//
+// minAvailableVersion must be lower or equal to the key min decryption and encryption versions.
+func (t *Transit) TrimKey(name string, minAvailableVersion int) error {
+	payload := map[string]int{"min_available_version": minAvailableVersion}
+	if err := t.do("POST", "keys/"+name+"/trim", payload, nil); err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return nil
+}
+
+// DeleteKey deletes the named key, the key config must allow deletion
+func (t *Transit) DeleteKey(name string) error {
+	if err := t.do("DELETE", "keys/"+name, nil, nil); err != nil {
+		return errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return nil
+}
+
+// ExportKey returns the named key versions (all if version is 0) of an exportable key.
+//
+// keyType is "encryption-key", "signing-key" or "hmac-key".
+//
+// Returns a map of version to key (base64 or PEM encoded depending on the key type).
+func (t *Transit) ExportKey(name, keyType string, version int) (map[int]string, error) {
+	var export struct {
+		Keys map[string]string `json:"keys"`
+	}
+	path := "export/" + keyType + "/" + name
+	if version > 0 {
+		path = path + "/" + strconv.Itoa(version)
+	}
+	if err := t.do("GET", path, nil, &export); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	keys := make(map[int]string, len(export.Keys))
+	for v, k := range export.Keys {
+		version, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		keys[version] = k
+	}
+	return keys, nil
+}
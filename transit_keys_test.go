@@ -0,0 +1,77 @@
+package vaultlib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTransit_KeyLifecycle(t *testing.T) {
+	conf := NewConfig()
+	conf.Token = "my-dev-root-vault-token"
+	vc, _ := NewClient(conf)
+	transit := vc.Transit("")
+
+	if err := transit.CreateKey("my-rotated-key", &TransitKeyCreateOptions{Exportable: true}); err != nil {
+		t.Fatalf("Transit.CreateKey() error = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := transit.RotateKey("my-rotated-key"); err != nil {
+			t.Fatalf("Transit.RotateKey() error = %v", err)
+		}
+	}
+	key, err := transit.ReadKey("my-rotated-key")
+	if err != nil {
+		t.Fatalf("Transit.ReadKey() error = %v", err)
+	}
+	if key.LatestVersion != 3 || !reflect.DeepEqual(key.VersionNumbers(), []int{1, 2, 3}) || !key.Exportable {
+		t.Errorf("Transit.ReadKey() = %v, want 3 versions", key)
+	}
+
+	minVersion := 2
+	deletionAllowed := true
+	err = transit.UpdateKeyConfig("my-rotated-key", &TransitKeyConfig{MinDecryptionVersion: &minVersion,
+		MinEncryptionVersion: &minVersion, DeletionAllowed: &deletionAllowed})
+	if err != nil {
+		t.Errorf("Transit.UpdateKeyConfig() error = %v", err)
+	}
+	if err = transit.TrimKey("my-rotated-key", 2); err != nil {
+		t.Errorf("Transit.TrimKey() error = %v", err)
+	}
+	key, _ = transit.ReadKey("my-rotated-key")
+	if key.MinDecryptionVersion != 2 || !reflect.DeepEqual(key.VersionNumbers(), []int{2, 3}) {
+		t.Errorf("Transit.ReadKey() after trim = %v, want versions 2 and 3", key)
+	}
+
+	exported, err := transit.ExportKey("my-rotated-key", "encryption-key", 0)
+	if err != nil || len(exported) != 2 || exported[3] == "" {
+		t.Errorf("Transit.ExportKey() = %v, error = %v", exported, err)
+	}
+	if _, err = transit.ExportKey("my-key", "encryption-key", 1); err == nil {
+		t.Errorf("Transit.ExportKey() of not exportable key should fail")
+	}
+
+	keys, err := transit.ListKeys()
+	if err != nil || len(keys) < 2 {
+		t.Errorf("Transit.ListKeys() = %v, error = %v", keys, err)
+	}
+	if err = transit.DeleteKey("my-rotated-key"); err != nil {
+		t.Errorf("Transit.DeleteKey() error = %v", err)
+	}
+	if _, err = transit.ReadKey("my-rotated-key"); err == nil {
+		t.Errorf("Transit.ReadKey() of deleted key should fail")
+	}
+}
+
+func TestTransit_ReadAsymmetricKey(t *testing.T) {
+	conf := NewConfig()
+	conf.Token = "my-dev-root-vault-token"
+	vc, _ := NewClient(conf)
+
+	key, err := vc.Transit("").ReadKey("my-ecdsa-key")
+	if err != nil {
+		t.Fatalf("Transit.ReadKey() error = %v", err)
+	}
+	if key.Type != "ecdsa-p256" || !key.SupportsSigning || key.Versions[1].PublicKey == "" || key.Versions[1].CreationTime.IsZero() {
+		t.Errorf("Transit.ReadKey() = %v", key)
+	}
+}
@@ -0,0 +1,109 @@
+package vaultlib
+
+import (
+	"github.com/pkg/errors"
+)
+
+// TransitSignOptions holds the optional parameters of the Transit sign, verify and hmac operations.
+//
+// KeyVersion is the key version to sign or hmac with (latest if 0).
+//
+// HashAlgorithm is the hash algorithm (ie "sha2-256", "sha2-512"), Vault default if empty.
+//
+// SignatureAlgorithm is the RSA signature algorithm ("pss" or "pkcs1v15").
+//
+// MarshalingAlgorithm is the ECDSA signature marshaling ("asn1" or "jws").
+//
+// Prehashed indicates the input is already hashed.
+//
+// Context is the key derivation context, required for derived keys.
+type TransitSignOptions struct {
+	KeyVersion          int
+	HashAlgorithm       string
+	SignatureAlgorithm  string
+	MarshalingAlgorithm string
+	Prehashed           bool
+	Context             []byte
+}
+
+// Sign signs the input with the named key.
+//
+// Returns the Vault signature (ie "vault:v1:...").
+func (t *Transit) Sign(key string, input []byte, opts *TransitSignOptions) (string, error) {
+	var rsp transitResponse
+	payload := newTransitSignRequest(opts)
+	payload.Input = input
+
+	if err := t.do("POST", "sign/"+key, payload, &rsp); err != nil {
+		return "", errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return rsp.Signature, nil
+}
+
+// Verify checks the signature of the input with the named key.
+//
+// Returns true if the signature is valid.
+func (t *Transit) Verify(key string, input []byte, signature string, opts *TransitSignOptions) (bool, error) {
+	var rsp transitResponse
+	payload := newTransitSignRequest(opts)
+	payload.KeyVersion = 0
+	payload.Input = input
+	payload.Signature = signature
+
+	if err := t.do("POST", "verify/"+key, payload, &rsp); err != nil {
+		return false, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return rsp.Valid, nil
+}
+
+// HMAC returns the HMAC of the input computed with the named key (ie "vault:v1:...").
+func (t *Transit) HMAC(key string, input []byte, opts *TransitSignOptions) (string, error) {
+	var rsp transitResponse
+	payload := newTransitHMACRequest(opts)
+	payload.Input = input
+
+	if err := t.do("POST", "hmac/"+key, payload, &rsp); err != nil {
+		return "", errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return rsp.HMAC, nil
+}
+
+// VerifyHMAC checks the HMAC of the input with the named key.
+//
+// Returns true if the HMAC is valid.
+func (t *Transit) VerifyHMAC(key string, input []byte, hmac string, opts *TransitSignOptions) (bool, error) {
+	var rsp transitResponse
+	payload := newTransitHMACRequest(opts)
+	payload.KeyVersion = 0
+	payload.Input = input
+	payload.HMAC = hmac
+
+	if err := t.do("POST", "verify/"+key, payload, &rsp); err != nil {
+		return false, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return rsp.Valid, nil
+}
+
+// newTransitSignRequest returns a sign request payload initialized with the options
+func newTransitSignRequest(opts *TransitSignOptions) *transitRequest {
+	payload := new(transitRequest)
+	if opts != nil {
+		payload.KeyVersion = opts.KeyVersion
+		payload.HashAlgorithm = opts.HashAlgorithm
+		payload.SignatureAlgorithm = opts.SignatureAlgorithm
+		payload.MarshalingAlgorithm = opts.MarshalingAlgorithm
+		payload.Prehashed = opts.Prehashed
+		payload.Context = opts.Context
+	}
+	return payload
+}
+
+// newTransitHMACRequest returns a hmac request payload initialized with the options
+func newTransitHMACRequest(opts *TransitSignOptions) *transitRequest {
+	payload := new(transitRequest)
+	if opts != nil {
+		payload.KeyVersion = opts.KeyVersion
+		payload.Algorithm = opts.HashAlgorithm
+	}
+	return payload
+}
@@ -0,0 +1,78 @@
+package vaultlib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTransit_SignVerify(t *testing.T) {
+	conf := NewConfig()
+	conf.Token = "my-dev-root-vault-token"
+	vc, _ := NewClient(conf)
+	transit := vc.Transit("")
+
+	tests := []struct {
+		name    string
+		key     string
+		opts    *TransitSignOptions
+		wantErr bool
+	}{
+		{"ecdsa", "my-ecdsa-key", nil, false},
+		{"ecdsaSha512", "my-ecdsa-key", &TransitSignOptions{HashAlgorithm: "sha2-512"}, false},
+		{"rsaPSS", "my-rsa-key", &TransitSignOptions{SignatureAlgorithm: "pss", KeyVersion: 1}, false},
+		{"rsaPKCS1", "my-rsa-key", &TransitSignOptions{SignatureAlgorithm: "pkcs1v15"}, false},
+		{"aesKey", "my-key", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := []byte("my-release-artifact")
+			signature, err := transit.Sign(tt.key, input, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Transit.Sign() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if !strings.HasPrefix(signature, "vault:v1:") {
+				t.Errorf("Transit.Sign() = %v, want vault:v1: prefix", signature)
+			}
+			if valid, err := transit.Verify(tt.key, input, signature, tt.opts); err != nil || !valid {
+				t.Errorf("Transit.Verify() = %v, error = %v, want true", valid, err)
+			}
+			if valid, err := transit.Verify(tt.key, []byte("tampered"), signature, tt.opts); err != nil || valid {
+				t.Errorf("Transit.Verify() tampered = %v, error = %v, want false", valid, err)
+			}
+		})
+	}
+}
+
+func TestTransit_HMAC(t *testing.T) {
+	conf := NewConfig()
+	conf.Token = "my-dev-root-vault-token"
+	vc, _ := NewClient(conf)
+	transit := vc.Transit("")
+
+	tests := []struct {
+		name string
+		opts *TransitSignOptions
+	}{
+		{"default", nil},
+		{"sha512", &TransitSignOptions{HashAlgorithm: "sha2-512", KeyVersion: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hmac, err := transit.HMAC("my-key", []byte("my-input"), tt.opts)
+			if err != nil || !strings.HasPrefix(hmac, "vault:v1:") {
+				t.Errorf("Transit.HMAC() = %v, error = %v", hmac, err)
+				return
+			}
+			if valid, err := transit.VerifyHMAC("my-key", []byte("my-input"), hmac, tt.opts); err != nil || !valid {
+				t.Errorf("Transit.VerifyHMAC() = %v, error = %v, want true", valid, err)
+			}
+			if valid, _ := transit.VerifyHMAC("my-key", []byte("other-input"), hmac, tt.opts); valid {
+				t.Errorf("Transit.VerifyHMAC() other input = %v, want false", valid)
+			}
+		})
+	}
+}
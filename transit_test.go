@@ -0,0 +1,119 @@
+package vaultlib
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTransit_EncryptDecrypt(t *testing.T) {
+	conf := NewConfig()
+	conf.Token = "my-dev-root-vault-token"
+	vc, _ := NewClient(conf)
+	transit := vc.Transit("")
+
+	tests := []struct {
+		name    string
+		key     string
+		opts    *TransitOptions
+		wantErr bool
+	}{
+		{"simple", "my-key", nil, false},
+		{"keyVersion", "my-key", &TransitOptions{KeyVersion: 1}, false},
+		{"derived", "my-derived-key", &TransitOptions{Context: []byte("my-context")}, false},
+		{"derivedNoContext", "my-derived-key", nil, true},
+		{"keyNotExist", "not-exist", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plaintext := []byte("my-pii-value")
+			ciphertext, err := transit.Encrypt(tt.key, plaintext, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Transit.Encrypt() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if !strings.HasPrefix(ciphertext, "vault:v1:") {
+				t.Errorf("Transit.Encrypt() = %v, want vault:v1: prefix", ciphertext)
+			}
+			got, err := transit.Decrypt(tt.key, ciphertext, tt.opts)
+			if err != nil || !reflect.DeepEqual(got, plaintext) {
+				t.Errorf("Transit.Decrypt() = %v, error = %v, want %v", string(got), err, string(plaintext))
+			}
+			rewrapped, err := transit.Rewrap(tt.key, ciphertext, tt.opts)
+			if err != nil || !strings.HasPrefix(rewrapped, "vault:v") {
+				t.Errorf("Transit.Rewrap() = %v, error = %v", rewrapped, err)
+			}
+		})
+	}
+
+	// Vault requires the plaintext field, even if empty
+	ciphertext, err := transit.Encrypt("my-key", nil, nil)
+	if err != nil {
+		t.Fatalf("Transit.Encrypt() empty plaintext error = %v", err)
+	}
+	if got, err := transit.Decrypt("my-key", ciphertext, nil); err != nil || len(got) != 0 {
+		t.Errorf("Transit.Decrypt() = %v, error = %v, want empty plaintext", got, err)
+	}
+}
+
+func TestTransit_Batch(t *testing.T) {
+	conf := NewConfig()
+	conf.Token = "my-dev-root-vault-token"
+	vc, _ := NewClient(conf)
+	transit := vc.Transit("transit")
+
+	items := []TransitBatchItem{
+		{Plaintext: []byte("first"), Context: []byte("ctx1")},
+		{Plaintext: []byte("second"), Context: []byte("ctx2")},
+	}
+	encrypted, err := transit.EncryptBatch("my-derived-key", items, nil)
+	if err != nil || len(encrypted) != 2 {
+		t.Fatalf("Transit.EncryptBatch() = %v, error = %v", encrypted, err)
+	}
+
+	decryptItems := []TransitBatchItem{
+		{Ciphertext: encrypted[0].Ciphertext, Context: []byte("ctx1")},
+		{Ciphertext: encrypted[1].Ciphertext, Context: []byte("wrong-ctx")},
+	}
+	decrypted, err := transit.DecryptBatch("my-derived-key", decryptItems, nil)
+	if err != nil {
+		t.Fatalf("Transit.DecryptBatch() error = %v", err)
+	}
+	if decrypted[0].Err != nil || string(decrypted[0].Plaintext) != "first" {
+		t.Errorf("Transit.DecryptBatch()[0] = %v, want first", decrypted[0])
+	}
+	if decrypted[1].Err == nil {
+		t.Errorf("Transit.DecryptBatch()[1] with wrong context should fail")
+	}
+
+	rewrapped, err := transit.RewrapBatch("my-derived-key", decryptItems[:1], nil)
+	if err != nil || rewrapped[0].Err != nil || rewrapped[0].Ciphertext == "" {
+		t.Errorf("Transit.RewrapBatch() = %v, error = %v", rewrapped, err)
+	}
+
+	// the options context applies to the items without their own context
+	opts := &TransitOptions{Context: []byte("ctx1")}
+	encrypted, err = transit.EncryptBatch("my-derived-key", []TransitBatchItem{{Plaintext: []byte("first")}}, opts)
+	if err != nil || encrypted[0].Err != nil {
+		t.Fatalf("Transit.EncryptBatch() options context = %v, error = %v", encrypted, err)
+	}
+	decrypted, err = transit.DecryptBatch("my-derived-key", []TransitBatchItem{
+		{Ciphertext: encrypted[0].Ciphertext},
+		{Ciphertext: encrypted[0].Ciphertext, Context: []byte("ctx2")},
+	}, opts)
+	if err != nil || decrypted[0].Err != nil || string(decrypted[0].Plaintext) != "first" || decrypted[1].Err == nil {
+		t.Errorf("Transit.DecryptBatch() options context = %v, error = %v", decrypted, err)
+	}
+
+	versioned, err := transit.EncryptBatch("my-key", []TransitBatchItem{{Plaintext: []byte("first")}, {}}, &TransitOptions{KeyVersion: 1})
+	if err != nil || versioned[0].Err != nil || versioned[0].KeyVersion != 1 || versioned[1].Err != nil {
+		t.Errorf("Transit.EncryptBatch() key version = %v, error = %v", versioned, err)
+	}
+
+	if _, err = transit.EncryptBatch("my-key", nil, nil); err == nil {
+		t.Errorf("Transit.EncryptBatch() without items should fail")
+	}
+}
@@ -2,6 +2,7 @@ package vaultlib
 
 import (
 	"encoding/json"
+	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -12,9 +13,12 @@ import (
 // KV contains data in case of KV secret.
 //
 // JSONSecret contains data in case of JSON raw secret.
+//
+// Lease contains the secret lease, if any.
 type Secret struct {
 	KV         map[string]string
 	JSONSecret json.RawMessage
+	Lease      Lease
 }
 
 type rawSecretData struct {
@@ -26,21 +30,36 @@ type rawSecretData struct {
 // KV: map[string]string if the secret is a KV
 //
 // JSONSecret: json.RawMessage if the secret is a json
+//
+// The secret is served from the cache if enabled (see EnableSecretCache).
 func (c *Client) GetSecret(path string) (secret Secret, err error) {
-	var v2Secret vaultSecretKV2
-	var vaultRsp rawSecretData
+	return c.GetSecretVersion(path, 0)
+}
+
+// GetSecretVersion returns the given version of a kv v2 secret, the latest version if 0.
+//
+// The secret is served from the cache if enabled (see EnableSecretCache).
+func (c *Client) GetSecretVersion(path string, version int) (Secret, error) {
+	if cache := c.getSecretCache(); cache != nil {
+		return cache.get(path, version, c.readSecret)
+	}
+	return c.readSecret(path, version)
+}
+
+// readSecret reads the secret version from Vault
+func (c *Client) readSecret(path string, version int) (secret Secret, err error) {
 	secret.KV = make(map[string]string)
 
 	kvVersion, kvName, err := c.getKVInfo(path)
 	if err != nil {
 		return secret, errors.Wrap(errors.WithStack(err), errInfo())
 	}
-	url := c.address.String()
-
-	if kvVersion == "2" {
-		url = url + "/v1/" + kvName + "data/" + strings.TrimPrefix(path, kvName)
-	} else {
-		url = url + "/v1/" + path
+	url := c.address.String() + "/v1/" + kvAPIPath(kvVersion, kvName, path)
+	if version > 0 {
+		if kvVersion != "2" {
+			return secret, errors.New("Secret versions are only supported by kv v2, " + path + " is kv v" + kvVersion)
+		}
+		url = url + "?version=" + strconv.Itoa(version)
 	}
 
 	req, _ := c.newRequest("GET", url)
@@ -49,47 +68,57 @@ func (c *Client) GetSecret(path string) (secret Secret, err error) {
 	if err != nil {
 		return secret, errors.Wrap(errors.WithStack(err), errInfo())
 	}
+	secret.Lease = leaseFromResponse(rsp)
+
+	if err = parseSecretData(kvVersion, rsp.Data, &secret); err != nil {
+		return secret, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return secret, nil
+}
+
+// parseSecretData parses the data of a kv version "1" or "2" response into secret
+func parseSecretData(kvVersion string, data json.RawMessage, secret *Secret) error {
+	var v2Secret vaultSecretKV2
+	var vaultRsp rawSecretData
+	if secret.KV == nil {
+		secret.KV = make(map[string]string)
+	}
 
 	if kvVersion == "2" {
-		err = json.Unmarshal([]byte(rsp.Data), &v2Secret)
+		err := json.Unmarshal([]byte(data), &v2Secret)
 		if err != nil {
-			return secret, errors.Wrap(errors.WithStack(err), errInfo())
+			return errors.Wrap(errors.WithStack(err), errInfo())
 		}
 		for k, v := range v2Secret.Data {
 			switch t := v.(type) {
 			case string:
 				secret.KV[k] = t
 			case interface{}:
-				//secret.JSONSecret = rsp.Data
 				//Parse twice to remove
-				err = json.Unmarshal([]byte(rsp.Data), &vaultRsp)
-				if err != nil {
-					return secret, err
-				}
-				err := json.Unmarshal([]byte(vaultRsp.Data), &secret.JSONSecret)
+				err = json.Unmarshal([]byte(data), &vaultRsp)
 				if err != nil {
-					return secret, err
+					return err
 				}
-				return secret, err
+				return json.Unmarshal([]byte(vaultRsp.Data), &secret.JSONSecret)
 			}
 		}
 	} else if kvVersion == "1" {
 		raw := make(map[string]interface{})
-		err = json.Unmarshal([]byte(rsp.Data), &raw)
+		err := json.Unmarshal([]byte(data), &raw)
 		if err != nil {
-			return secret, errors.Wrap(errors.WithStack(err), errInfo())
+			return errors.Wrap(errors.WithStack(err), errInfo())
 		}
 		for k, v := range raw {
 			switch t := v.(type) {
 			case string:
 				secret.KV[k] = t
 			case interface{}:
-				secret.JSONSecret = rsp.Data
-				return secret, err
+				secret.JSONSecret = data
+				return nil
 			}
 		}
 	}
-	return secret, nil
+	return nil
 }
 
 // vaultMountResponse holds the Vault Mount list response (used to unmarshall the global vault response)
@@ -116,6 +145,26 @@ type vaultSecretMounts struct {
 }
 
 func (c *Client) getKVInfo(path string) (version, name string, err error) {
+	// cubbyhole is not a kv mount and may not be listed, but follows the kv v1 API
+	if strings.HasPrefix(path, cubbyholeMount) {
+		return "1", cubbyholeMount, nil
+	}
+
+	mounts, err := c.getSecretMounts()
+	if err != nil {
+		return "", "", errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	version, name = kvInfoFromMounts(mounts, path)
+	if len(version) == 0 {
+		return "", "", errors.WithStack(&KVMountNotFoundError{Path: path})
+	}
+	return version, name, nil
+
+}
+
+// getSecretMounts returns the secret engines visible by the client's token
+func (c *Client) getSecretMounts() (map[string]vaultSecretMounts, error) {
 	var mountResponse vaultMountResponse
 	var vaultSecretMount = make(map[string]vaultSecretMounts)
 	url := c.address.String() + "/v1/sys/internal/ui/mounts"
@@ -124,20 +173,25 @@ func (c *Client) getKVInfo(path string) (version, name string, err error) {
 
 	rsp, err := req.execute()
 	if err != nil {
-		return "", "", errors.Wrap(errors.WithStack(err), errInfo())
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
 	}
 
 	jsonErr := json.Unmarshal([]byte(rsp.Data), &mountResponse)
 	if jsonErr != nil {
-		return "", "", errors.Wrap(errors.WithStack(err), errInfo())
+		return nil, errors.Wrap(errors.WithStack(jsonErr), errInfo())
 	}
 
 	jsonErr = json.Unmarshal([]byte(mountResponse.Secret), &vaultSecretMount)
 	if jsonErr != nil {
-		return "", "", errors.Wrap(errors.WithStack(err), errInfo())
+		return nil, errors.Wrap(errors.WithStack(jsonErr), errInfo())
 	}
+	return vaultSecretMount, nil
+}
 
-	for kvName, v := range vaultSecretMount {
+// kvInfoFromMounts returns the kv version and mount name matching the path,
+// empty strings if the path does not belong to any mount
+func kvInfoFromMounts(mounts map[string]vaultSecretMounts, path string) (version, name string) {
+	for kvName, v := range mounts {
 		if strings.HasPrefix(path, kvName) {
 			name = kvName
 			if len(v.Options) > 0 {
@@ -152,9 +206,14 @@ func (c *Client) getKVInfo(path string) (version, name string, err error) {
 			break
 		}
 	}
-	if len(version) == 0 {
-		return "", "", errors.New("Could not get kv version")
-	}
-	return version, name, nil
+	return version, name
+}
 
+// kvAPIPath returns the Vault API path of a secret (without /v1/ prefix),
+// kv v2 secrets are translated to their "data/" path
+func kvAPIPath(kvVersion, kvName, path string) string {
+	if kvVersion == "2" {
+		return kvName + "data/" + strings.TrimPrefix(path, kvName)
+	}
+	return path
 }
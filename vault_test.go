@@ -6,6 +6,8 @@ import (
 	"reflect"
 	"testing"
 	"time"
+
+	"github.com/mch1307/vaultlib/vaulttest"
 )
 
 func TestVaultClient_getKVInfo(t *testing.T) {
@@ -41,6 +43,7 @@ func TestVaultClient_getKVInfo(t *testing.T) {
 	}{
 		{"foundV1", fields{conf}, args{"kv_v1/path/my-secret"}, "1", "kv_v1/path/", false},
 		{"foundV2", fields{conf}, args{"kv_v2/path/my-secret"}, "2", "kv_v2/path/", false},
+		{"cubbyhole", fields{conf}, args{"cubbyhole/my-secret"}, "1", "cubbyhole/", false},
 		{"notFound", fields{conf}, args{"notExist/my-secret"}, "", "", true},
 		{"badRequest", fields{badReqConf}, args{"notExist/my-secret"}, "", "", true},
 		{"NoCred", fields{noCredConf}, args{"notExist/my-secret"}, "", "", true},
@@ -113,3 +116,20 @@ func TestVaultClient_GetSecret(t *testing.T) {
 		})
 	}
 }
+
+// newTestVault returns a fake Vault server with a kv v1 mount at kv_v1/ and a kv v2 mount at kv_v2/,
+// and a client of the server authenticated with its root token
+func newTestVault(t *testing.T) (*vaulttest.Server, *Client) {
+	srv := vaulttest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.MountKV("kv_v1", 1)
+	srv.MountKV("kv_v2", 2)
+	conf := NewConfig()
+	conf.Address = srv.URL
+	conf.Token = vaulttest.RootToken
+	vc, err := NewClient(conf)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return srv, vc
+}
@@ -0,0 +1,280 @@
+package vaulttest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// kvV1LeaseDuration is the lease duration returned with the kv v1 secrets (Vault default, 768h)
+const kvV1LeaseDuration = 2764800
+
+// kvMount is a kv secrets engine
+type kvMount struct {
+	kvVersion int
+	secrets   map[string]*kvSecret
+}
+
+// kvSecret is a kv secret, kv v1 secrets have a single version
+type kvSecret struct {
+	versions       []*kvVersion
+	customMetadata map[string]string
+	createdTime    time.Time
+	updatedTime    time.Time
+}
+
+// kvVersion is a kv secret version
+type kvVersion struct {
+	data         map[string]interface{}
+	createdTime  time.Time
+	deletionTime time.Time
+	destroyed    bool
+}
+
+func (v *kvVersion) deleted() bool {
+	return !v.deletionTime.IsZero() || v.destroyed
+}
+
+// metadata returns the kv v2 version metadata
+func (v *kvVersion) metadata(number int) map[string]interface{} {
+	deletion := ""
+	if !v.deletionTime.IsZero() {
+		deletion = v.deletionTime.Format(time.RFC3339Nano)
+	}
+	return map[string]interface{}{
+		"created_time":  v.createdTime.Format(time.RFC3339Nano),
+		"deletion_time": deletion,
+		"destroyed":     v.destroyed,
+		"version":       number,
+	}
+}
+
+// put writes a new version of the secret (replaces the secret with kv v1), returns its number
+func (m *kvMount) put(key string, data map[string]interface{}) int {
+	now := time.Now()
+	secret, ok := m.secrets[key]
+	if !ok || m.kvVersion != 2 {
+		secret = &kvSecret{createdTime: now}
+		m.secrets[key] = secret
+	}
+	secret.versions = append(secret.versions, &kvVersion{data: data, createdTime: now})
+	secret.updatedTime = now
+	return len(secret.versions)
+}
+
+// version returns the secret version, the latest if number is 0
+func (m *kvMount) version(key string, number int) (*kvVersion, bool) {
+	secret, ok := m.secrets[key]
+	if !ok {
+		return nil, false
+	}
+	if number == 0 {
+		number = len(secret.versions)
+	}
+	if number < 1 || number > len(secret.versions) {
+		return nil, false
+	}
+	return secret.versions[number-1], true
+}
+
+// list writes the keys under folder, sub folders end with "/"
+func (m *kvMount) list(w http.ResponseWriter, folder string) {
+	if folder = strings.Trim(folder, "/"); folder != "" {
+		folder += "/"
+	}
+	keys := make(map[string]bool)
+	for key := range m.secrets {
+		if !strings.HasPrefix(key, folder) {
+			continue
+		}
+		child := strings.TrimPrefix(key, folder)
+		if i := strings.Index(child, "/"); i >= 0 {
+			child = child[:i+1]
+		}
+		keys[child] = true
+	}
+	if len(keys) == 0 {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, map[string]interface{}{"data": map[string]interface{}{"keys": sortedKeys(keys)}})
+}
+
+// serve handles the request to the mount, key is the path in the mount
+func (m *kvMount) serve(w http.ResponseWriter, r *http.Request, method, key string, body map[string]interface{}) {
+	if m.kvVersion != 2 {
+		m.serveV1(w, method, key, body)
+		return
+	}
+	endpoint := key
+	if i := strings.Index(key, "/"); i >= 0 {
+		endpoint, key = key[:i], key[i+1:]
+	} else {
+		key = ""
+	}
+
+	switch {
+	case endpoint == "data" && method == "GET":
+		number, _ := strconv.Atoi(r.URL.Query().Get("version"))
+		m.read(w, key, number)
+	case endpoint == "data" && method == "POST":
+		data, _ := body["data"].(map[string]interface{})
+		if data == nil {
+			writeError(w, http.StatusBadRequest, "no data provided")
+			return
+		}
+		if options, ok := body["options"].(map[string]interface{}); ok {
+			if cas, ok := options["cas"].(float64); ok {
+				current := 0
+				if secret, ok := m.secrets[key]; ok {
+					current = len(secret.versions)
+				}
+				if int(cas) != current {
+					writeError(w, http.StatusBadRequest, "check-and-set parameter did not match the current version")
+					return
+				}
+			}
+		}
+		number := m.put(key, data)
+		version, _ := m.version(key, number)
+		writeJSON(w, map[string]interface{}{"data": version.metadata(number)})
+	case endpoint == "data" && method == "DELETE":
+		if version, ok := m.version(key, 0); ok && version.deletionTime.IsZero() {
+			version.deletionTime = time.Now()
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case endpoint == "metadata" && method == "LIST":
+		m.list(w, key)
+	case endpoint == "metadata" && method == "GET":
+		m.readMetadata(w, key)
+	case endpoint == "metadata" && method == "POST":
+		secret, ok := m.secrets[key]
+		if !ok {
+			secret = &kvSecret{createdTime: time.Now(), updatedTime: time.Now()}
+			m.secrets[key] = secret
+		}
+		if custom, ok := body["custom_metadata"].(map[string]interface{}); ok {
+			secret.customMetadata = make(map[string]string, len(custom))
+			for k, v := range custom {
+				secret.customMetadata[k], _ = v.(string)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case endpoint == "metadata" && method == "DELETE":
+		delete(m.secrets, key)
+		w.WriteHeader(http.StatusNoContent)
+	case (endpoint == "delete" || endpoint == "undelete" || endpoint == "destroy") && method == "POST":
+		versions, _ := body["versions"].([]interface{})
+		for _, v := range versions {
+			number, _ := v.(float64)
+			version, ok := m.version(key, int(number))
+			if !ok || number == 0 {
+				continue
+			}
+			switch endpoint {
+			case "delete":
+				if version.deletionTime.IsZero() {
+					version.deletionTime = time.Now()
+				}
+			case "undelete":
+				if !version.destroyed {
+					version.deletionTime = time.Time{}
+				}
+			case "destroy":
+				version.destroyed, version.data = true, nil
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "unsupported operation")
+	}
+}
+
+// serveV1 handles the kv v1 requests
+func (m *kvMount) serveV1(w http.ResponseWriter, method, key string, body map[string]interface{}) {
+	switch method {
+	case "LIST":
+		m.list(w, key)
+	case "GET":
+		version, ok := m.version(key, 0)
+		if !ok {
+			writeError(w, http.StatusNotFound)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"data": version.data, "lease_duration": kvV1LeaseDuration, "renewable": false})
+	case "POST":
+		if body == nil {
+			body = map[string]interface{}{}
+		}
+		m.put(key, body)
+		w.WriteHeader(http.StatusNoContent)
+	case "DELETE":
+		delete(m.secrets, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "unsupported operation")
+	}
+}
+
+// read writes the kv v2 secret version, a 404 with the version metadata if deleted or destroyed
+func (m *kvMount) read(w http.ResponseWriter, key string, number int) {
+	version, ok := m.version(key, number)
+	if !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	if number == 0 {
+		number = len(m.secrets[key].versions)
+	}
+	metadata := version.metadata(number)
+	metadata["custom_metadata"] = m.secrets[key].customMetadata
+	if version.deleted() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": nil, "metadata": metadata}})
+		return
+	}
+	writeJSON(w, map[string]interface{}{"data": map[string]interface{}{"data": version.data, "metadata": metadata}})
+}
+
+// readMetadata writes the kv v2 secret metadata
+func (m *kvMount) readMetadata(w http.ResponseWriter, key string) {
+	secret, ok := m.secrets[key]
+	if !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	versions := make(map[string]interface{}, len(secret.versions))
+	for i, version := range secret.versions {
+		metadata := version.metadata(i + 1)
+		delete(metadata, "version")
+		versions[strconv.Itoa(i+1)] = metadata
+	}
+	oldest := 0
+	if len(secret.versions) > 0 {
+		oldest = 1
+	}
+	writeJSON(w, map[string]interface{}{"data": map[string]interface{}{
+		"current_version": len(secret.versions),
+		"oldest_version":  oldest,
+		"max_versions":    0,
+		"cas_required":    false,
+		"created_time":    secret.createdTime.Format(time.RFC3339Nano),
+		"updated_time":    secret.updatedTime.Format(time.RFC3339Nano),
+		"custom_metadata": secret.customMetadata,
+		"versions":        versions,
+	}})
+}
+
+// copyData returns a deep copy of the secret data
+func copyData(data map[string]interface{}) map[string]interface{} {
+	if data == nil {
+		return nil
+	}
+	var copied map[string]interface{}
+	encoded, _ := json.Marshal(data)
+	_ = json.Unmarshal(encoded, &copied)
+	return copied
+}
@@ -0,0 +1,501 @@
+// Package vaulttest provides an in-memory fake Vault server, to test vaultlib clients
+// (or any Vault HTTP API client) without a Vault server.
+//
+// The fake implements:
+//
+//	sys/internal/ui/mounts, sys/health, sys/seal-status
+//	kv v1 and v2 secrets: read, write, list, delete, kv v2 metadata, versions, delete/undelete/destroy
+//	auth/token/lookup-self, renew-self and revoke-self
+//	AppRole login (auth/<mount>/login)
+//	namespaces, from the X-Vault-Namespace header or the path prefix
+//
+// The tokens are checked but the policies are not enforced. Latency, errors and the sealed
+// state can be injected with SetLatency, SetErrorHook and SetSealed. The other endpoints
+// can be served by the test with HandleFunc.
+//
+//	srv := vaulttest.NewServer()
+//	defer srv.Close()
+//	srv.PutSecret("secret/my-secret", map[string]interface{}{"password": "s3cr3t"})
+//
+//	cfg := vaultlib.NewConfig()
+//	cfg.Address = srv.URL
+//	cfg.Token = vaulttest.RootToken
+//	client, err := vaultlib.NewClient(cfg)
+package vaulttest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RootToken is the root token of the fake server, valid in all the namespaces and never expiring
+const RootToken = "root"
+
+// Server is an in-memory fake Vault server.
+//
+// The root namespace has a kv v2 mount at "secret/", like a Vault dev server.
+type Server struct {
+	*httptest.Server
+	mu         sync.Mutex
+	namespaces map[string]*Namespace
+	tokens     map[string]*token
+	latency    time.Duration
+	sealed     bool
+	errorHook  func(r *http.Request) int
+	handlers   map[string]http.HandlerFunc
+	requests   []string
+}
+
+// Namespace holds the mounts and AppRoles of a namespace
+type Namespace struct {
+	server   *Server
+	name     string
+	mounts   map[string]*kvMount
+	appRoles map[string]AppRole
+}
+
+// AppRole holds the credentials of an AppRole, logging in with them gives a token
+// with the policies, valid for TTL (never expiring if 0). Mount defaults to "approle".
+type AppRole struct {
+	Mount    string
+	RoleName string
+	RoleID   string
+	SecretID string
+	Policies []string
+	TTL      time.Duration
+}
+
+// token holds a token issued by the server
+type token struct {
+	id          string
+	accessor    string
+	policies    []string
+	ttl         time.Duration
+	issueTime   time.Time
+	expireTime  time.Time
+	path        string
+	displayName string
+	meta        map[string]string
+}
+
+// NewServer starts a fake Vault server, stop it with Close
+func NewServer() *Server {
+	s := &Server{
+		namespaces: make(map[string]*Namespace),
+		tokens:     make(map[string]*token),
+		handlers:   make(map[string]http.HandlerFunc),
+	}
+	s.tokens[RootToken] = &token{id: RootToken, accessor: newID(), policies: []string{"root"},
+		issueTime: time.Now(), path: "auth/token/root", displayName: "root"}
+	s.Namespace("").MountKV("secret/", 2)
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Namespace returns the namespace (ie "ns1" or "ns1/ns2"), created with its parents if needed.
+// The root namespace is "".
+func (s *Server) Namespace(name string) *Namespace {
+	name = strings.Trim(name, "/")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ns *Namespace
+	parts := strings.Split(name, "/")
+	for i := range parts {
+		parent := strings.Join(parts[:i+1], "/")
+		var ok bool
+		if ns, ok = s.namespaces[parent]; !ok {
+			ns = &Namespace{server: s, name: parent, mounts: make(map[string]*kvMount), appRoles: make(map[string]AppRole)}
+			s.namespaces[parent] = ns
+		}
+	}
+	return ns
+}
+
+// MountKV mounts a kv secrets engine of the version (1 or 2) at path in the root namespace
+func (s *Server) MountKV(path string, version int) {
+	s.Namespace("").MountKV(path, version)
+}
+
+// PutSecret writes the secret at path (ie "secret/my-secret") in the root namespace
+func (s *Server) PutSecret(path string, data map[string]interface{}) {
+	s.Namespace("").PutSecret(path, data)
+}
+
+// Secret returns the latest data of the secret at path in the root namespace,
+// false if it does not exist or is deleted
+func (s *Server) Secret(path string) (map[string]interface{}, bool) {
+	return s.Namespace("").Secret(path)
+}
+
+// AddAppRole adds the AppRole to the root namespace
+func (s *Server) AddAppRole(role AppRole) {
+	s.Namespace("").AddAppRole(role)
+}
+
+// CreateToken returns a new token with the policies, valid for ttl (never expiring if 0)
+func (s *Server) CreateToken(ttl time.Duration, policies ...string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.createToken(ttl, policies, "auth/token/create", "token", nil).id
+}
+
+// SetLatency delays all the responses
+func (s *Server) SetLatency(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = latency
+}
+
+// SetSealed seals (all the requests fail with a 503) or unseals the server
+func (s *Server) SetSealed(sealed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sealed = sealed
+}
+
+// SetErrorHook sets a function called before each request is handled: if it returns
+// a non zero http status, the request fails with this status. nil removes the hook.
+func (s *Server) SetErrorHook(hook func(r *http.Request) int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errorHook = hook
+}
+
+// HandleFunc serves the API path (ie "sys/leases/renew", without "/v1/") with handler,
+// for the endpoints the fake does not implement. A path ending with "/" serves all the paths under it.
+// The handlers are called after the token check, whatever the namespace, before the kv mounts.
+func (s *Server) HandleFunc(path string, handler http.HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[strings.TrimPrefix(path, "/")] = handler
+}
+
+// Requests returns the requests received by the server ("METHOD /v1/path"), oldest first
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// MountKV mounts a kv secrets engine of the version (1 or 2) at path (ie "kv/")
+func (ns *Namespace) MountKV(path string, version int) {
+	ns.server.mu.Lock()
+	defer ns.server.mu.Unlock()
+	path = strings.Trim(path, "/") + "/"
+	ns.mounts[path] = &kvMount{kvVersion: version, secrets: make(map[string]*kvSecret)}
+}
+
+// PutSecret writes the secret at path (mount included), a new version with kv v2.
+// It panics if path does not belong to a kv mount.
+func (ns *Namespace) PutSecret(path string, data map[string]interface{}) {
+	ns.server.mu.Lock()
+	defer ns.server.mu.Unlock()
+	mount, key := ns.mount(path)
+	if mount == nil {
+		panic("vaulttest: no kv mount for " + path)
+	}
+	mount.put(key, copyData(data))
+}
+
+// Secret returns the latest data of the secret at path, false if it does not exist or is deleted
+func (ns *Namespace) Secret(path string) (map[string]interface{}, bool) {
+	ns.server.mu.Lock()
+	defer ns.server.mu.Unlock()
+	mount, key := ns.mount(path)
+	if mount == nil {
+		return nil, false
+	}
+	version, ok := mount.version(key, 0)
+	if !ok || version.deleted() {
+		return nil, false
+	}
+	return copyData(version.data), true
+}
+
+// AddAppRole adds the AppRole credentials
+func (ns *Namespace) AddAppRole(role AppRole) {
+	ns.server.mu.Lock()
+	defer ns.server.mu.Unlock()
+	if role.Mount == "" {
+		role.Mount = "approle"
+	}
+	ns.appRoles[strings.Trim(role.Mount, "/")+"/"+role.RoleID] = role
+}
+
+// mount returns the kv mount of path and the secret key in the mount, must be called with the lock held
+func (ns *Namespace) mount(path string) (*kvMount, string) {
+	path = strings.TrimPrefix(path, "/")
+	var name string
+	for mountPath := range ns.mounts {
+		if strings.HasPrefix(path, mountPath) && len(mountPath) > len(name) {
+			name = mountPath
+		}
+	}
+	if name == "" {
+		return nil, ""
+	}
+	return ns.mounts[name], strings.TrimPrefix(path, name)
+}
+
+// serveHTTP handles all the requests
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	latency, hook := s.latency, s.errorHook
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if hook != nil {
+		if status := hook(r); status != 0 {
+			writeError(w, status, http.StatusText(status))
+			return
+		}
+	}
+
+	var raw []byte
+	if r.Body != nil {
+		raw, _ = ioutil.ReadAll(r.Body)
+	}
+	var body map[string]interface{}
+	_ = json.Unmarshal(raw, &body)
+	method := r.Method
+	if method == "PUT" {
+		method = "POST"
+	}
+	if method == "GET" && r.URL.Query().Get("list") == "true" {
+		method = "LIST"
+	}
+
+	s.mu.Lock()
+	handler := s.route(w, r, method, body)
+	s.mu.Unlock()
+	// the handlers of the tests are called without the lock, they may call the server
+	if handler != nil {
+		r.Body = ioutil.NopCloser(bytes.NewReader(raw))
+		handler(w, r)
+	}
+}
+
+// route handles the request, or returns the HandleFunc handler serving it.
+// Must be called with the lock held.
+func (s *Server) route(w http.ResponseWriter, r *http.Request, method string, body map[string]interface{}) http.HandlerFunc {
+	if !strings.HasPrefix(r.URL.Path, "/v1/") {
+		writeError(w, http.StatusNotFound)
+		return nil
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+
+	switch path {
+	case "sys/health", "sys/seal-status":
+		writeJSON(w, map[string]interface{}{"initialized": true, "sealed": s.sealed})
+		return nil
+	}
+	if s.sealed {
+		writeError(w, http.StatusServiceUnavailable, "Vault is sealed")
+		return nil
+	}
+
+	ns, path := s.resolveNamespace(r.Header.Get("X-Vault-Namespace"), path)
+	if ns == nil {
+		writeError(w, http.StatusNotFound, "no handler for route")
+		return nil
+	}
+	if strings.HasPrefix(path, "auth/") && strings.HasSuffix(path, "/login") && method == "POST" {
+		ns.login(w, strings.TrimSuffix(strings.TrimPrefix(path, "auth/"), "/login"), body)
+		return nil
+	}
+
+	tk, ok := s.tokens[r.Header.Get("X-Vault-Token")]
+	if !ok || (!tk.expireTime.IsZero() && time.Now().After(tk.expireTime)) {
+		writeError(w, http.StatusForbidden, "permission denied")
+		return nil
+	}
+
+	if handler := s.handler(path); handler != nil {
+		return handler
+	}
+
+	switch {
+	case path == "sys/internal/ui/mounts" && method == "GET":
+		ns.serveMounts(w)
+	case path == "auth/token/lookup-self" && (method == "GET" || method == "POST"):
+		writeJSON(w, map[string]interface{}{"data": tk.info()})
+	case path == "auth/token/renew-self" && method == "POST":
+		if tk.ttl > 0 {
+			tk.expireTime = time.Now().Add(tk.ttl)
+		}
+		writeJSON(w, map[string]interface{}{"auth": tk.auth()})
+	case path == "auth/token/revoke-self" && method == "POST":
+		if tk.id != RootToken {
+			delete(s.tokens, tk.id)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		mount, key := ns.mount(path)
+		if mount == nil {
+			writeError(w, http.StatusNotFound, "no handler for route")
+			return nil
+		}
+		mount.serve(w, r, method, key, body)
+	}
+	return nil
+}
+
+// handler returns the HandleFunc handler of the path, the longest matching one.
+// Must be called with the lock held.
+func (s *Server) handler(path string) http.HandlerFunc {
+	var match string
+	for prefix := range s.handlers {
+		if (prefix == path || (strings.HasSuffix(prefix, "/") && strings.HasPrefix(path, prefix))) && len(prefix) > len(match) {
+			match = prefix
+		}
+	}
+	return s.handlers[match]
+}
+
+// resolveNamespace returns the namespace of the request, from the header and the path prefix,
+// and the path without the namespace prefix. Must be called with the lock held.
+func (s *Server) resolveNamespace(header, path string) (*Namespace, string) {
+	name := strings.Trim(header, "/")
+	// the path may start with child namespaces of the header namespace
+	for {
+		i := strings.Index(path, "/")
+		if i < 0 {
+			break
+		}
+		child := strings.Trim(name+"/"+path[:i], "/")
+		if _, ok := s.namespaces[child]; !ok {
+			break
+		}
+		name, path = child, path[i+1:]
+	}
+	return s.namespaces[name], path
+}
+
+// serveMounts returns the namespace kv mounts
+func (ns *Namespace) serveMounts(w http.ResponseWriter) {
+	mounts := make(map[string]interface{}, len(ns.mounts))
+	for path, mount := range ns.mounts {
+		var options map[string]string
+		if mount.kvVersion == 2 {
+			options = map[string]string{"version": "2"}
+		}
+		mounts[path] = map[string]interface{}{"type": "kv", "options": options, "local": false, "seal_wrap": false}
+	}
+	writeJSON(w, map[string]interface{}{"data": map[string]interface{}{"auth": map[string]interface{}{}, "secret": mounts}})
+}
+
+// login logs in with the AppRole credentials, must be called with the lock held
+func (ns *Namespace) login(w http.ResponseWriter, mount string, body map[string]interface{}) {
+	roleID, _ := body["role_id"].(string)
+	secretID, _ := body["secret_id"].(string)
+	role, ok := ns.appRoles[mount+"/"+roleID]
+	if !ok || role.SecretID != secretID {
+		writeError(w, http.StatusBadRequest, "invalid role or secret ID")
+		return
+	}
+	tk := ns.server.createToken(role.TTL, role.Policies, "auth/"+mount+"/login", mount, map[string]string{"role_name": role.RoleName})
+	writeJSON(w, map[string]interface{}{"auth": tk.auth()})
+}
+
+// createToken creates a new token, must be called with the lock held
+func (s *Server) createToken(ttl time.Duration, policies []string, path, displayName string, meta map[string]string) *token {
+	tk := &token{id: "s." + newID(), accessor: newID(), policies: append([]string{"default"}, policies...),
+		ttl: ttl, issueTime: time.Now(), path: path, displayName: displayName, meta: meta}
+	if ttl > 0 {
+		tk.expireTime = tk.issueTime.Add(ttl)
+	}
+	s.tokens[tk.id] = tk
+	return tk
+}
+
+// info returns the token lookup data
+func (tk *token) info() map[string]interface{} {
+	info := map[string]interface{}{
+		"id":               tk.id,
+		"accessor":         tk.accessor,
+		"policies":         tk.policies,
+		"path":             tk.path,
+		"display_name":     tk.displayName,
+		"meta":             tk.meta,
+		"creation_time":    tk.issueTime.Unix(),
+		"creation_ttl":     int(tk.ttl.Seconds()),
+		"issue_time":       tk.issueTime.Format(time.RFC3339Nano),
+		"expire_time":      nil,
+		"ttl":              0,
+		"renewable":        tk.ttl > 0,
+		"type":             "service",
+		"explicit_max_ttl": 0,
+		"num_uses":         0,
+		"orphan":           tk.id == RootToken,
+	}
+	if !tk.expireTime.IsZero() {
+		info["expire_time"] = tk.expireTime.Format(time.RFC3339Nano)
+		info["ttl"] = int(time.Until(tk.expireTime).Seconds())
+	}
+	return info
+}
+
+// auth returns the token auth response data
+func (tk *token) auth() map[string]interface{} {
+	ttl := 0
+	if !tk.expireTime.IsZero() {
+		ttl = int(time.Until(tk.expireTime).Seconds())
+	}
+	return map[string]interface{}{
+		"client_token":   tk.id,
+		"accessor":       tk.accessor,
+		"policies":       tk.policies,
+		"token_policies": tk.policies,
+		"metadata":       tk.meta,
+		"lease_duration": ttl,
+		"renewable":      tk.ttl > 0,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a Vault error response
+func writeError(w http.ResponseWriter, status int, errs ...string) {
+	if errs == nil {
+		errs = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"errors": errs})
+}
+
+// newID returns a random identifier
+func newID() string {
+	id := make([]byte, 12)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// sortedKeys returns the map keys, sorted
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
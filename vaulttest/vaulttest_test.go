@@ -0,0 +1,203 @@
+package vaulttest_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	vault "github.com/mch1307/vaultlib"
+	"github.com/mch1307/vaultlib/vaulttest"
+	"github.com/pkg/errors"
+)
+
+// newClient returns a vaultlib client of the server, authenticated with the token
+func newClient(t *testing.T, srv *vaulttest.Server, token, namespace string) *vault.Client {
+	cfg := vault.NewConfig()
+	cfg.Address = srv.URL
+	cfg.Token = token
+	cfg.Namespace = namespace
+	client, err := vault.NewClient(cfg)
+	if err != nil {
+		t.Fatalf("vaultlib.NewClient() error = %v", err)
+	}
+	return client
+}
+
+// statusCode returns the http status of a vaultlib error, 0 if not a Vault response error
+func statusCode(err error) int {
+	if rspErr, ok := errors.Cause(err).(*vault.ResponseError); ok {
+		return rspErr.StatusCode
+	}
+	return 0
+}
+
+func TestServer_KV(t *testing.T) {
+	srv := vaulttest.NewServer()
+	defer srv.Close()
+	srv.MountKV("kv", 1)
+	srv.PutSecret("secret/app/db", map[string]interface{}{"user": "app", "password": "old"})
+	srv.PutSecret("secret/app/db", map[string]interface{}{"user": "app", "password": "s3cr3t"})
+	srv.PutSecret("kv/app/db", map[string]interface{}{"user": "app1"})
+	client := newClient(t, srv, vaulttest.RootToken, "")
+
+	for path, want := range map[string]map[string]string{
+		"secret/app/db": {"user": "app", "password": "s3cr3t"},
+		"kv/app/db":     {"user": "app1"},
+	} {
+		secret, err := client.GetSecret(path)
+		if err != nil || !reflect.DeepEqual(secret.KV, want) {
+			t.Errorf("Client.GetSecret(%v) = %v, %v, want %v", path, secret.KV, err, want)
+		}
+	}
+	if secret, err := client.GetSecretVersion("secret/app/db", 1); err != nil || secret.KV["password"] != "old" {
+		t.Errorf("Client.GetSecretVersion() = %v, %v", secret.KV, err)
+	}
+
+	for _, mount := range []string{"secret/", "kv/"} {
+		if err := client.PutSecret(mount+"app/sub/api", map[string]interface{}{"token": "abcd"}); err != nil {
+			t.Errorf("Client.PutSecret() error = %v", err)
+		}
+		if data, ok := srv.Secret(mount + "app/sub/api"); !ok || data["token"] != "abcd" {
+			t.Errorf("Server.Secret() = %v, %v", data, ok)
+		}
+		keys, err := client.ListSecrets(mount + "app")
+		if err != nil || !reflect.DeepEqual(keys, []string{"db", "sub/"}) {
+			t.Errorf("Client.ListSecrets(%v) = %v, %v", mount, keys, err)
+		}
+		if err = client.DeleteSecret(mount + "app/sub/api"); err != nil {
+			t.Errorf("Client.DeleteSecret() error = %v", err)
+		}
+		if _, err = client.GetSecret(mount + "app/sub/api"); statusCode(err) != http.StatusNotFound {
+			t.Errorf("Client.GetSecret() deleted secret error = %v, want a 404", err)
+		}
+	}
+
+	// kv v2 versions and metadata survive an export / import
+	export, err := client.ExportKV("secret/app", vault.KVExportOptions{AllVersions: true, Metadata: true})
+	if err != nil || len(export.Secrets) != 2 || len(export.Secrets[0].Versions) != 2 {
+		t.Fatalf("Client.ExportKV() = %+v, %v", export, err)
+	}
+	report, err := client.ImportKV("secret/copy", export, vault.KVImportOptions{})
+	if err != nil || !reflect.DeepEqual(report.Created, []string{"secret/copy/db"}) {
+		t.Errorf("Client.ImportKV() = %+v, %v", report, err)
+	}
+	if secret, err := client.GetSecretVersion("secret/copy/db", 1); err != nil || secret.KV["password"] != "old" {
+		t.Errorf("Client.GetSecretVersion() imported = %v, %v", secret.KV, err)
+	}
+}
+
+func TestServer_Auth(t *testing.T) {
+	srv := vaulttest.NewServer()
+	defer srv.Close()
+	srv.AddAppRole(vaulttest.AppRole{RoleName: "my-role", RoleID: "role", SecretID: "secret", Policies: []string{"app"}, TTL: time.Hour})
+
+	cfg := vault.NewConfig()
+	cfg.Address = srv.URL
+	cfg.Token = ""
+	cfg.AppRoleCredentials.RoleID = "role"
+	cfg.AppRoleCredentials.SecretID = "secret"
+	client, err := vault.NewClient(cfg)
+	if err != nil {
+		t.Fatalf("vaultlib.NewClient() AppRole error = %v", err)
+	}
+	info := client.GetTokenInfo()
+	if !reflect.DeepEqual(info.Policies, []string{"default", "app"}) || info.Metadata()["role_name"] != "my-role" ||
+		!info.Renewable || info.Expiration() == nil || info.TTLDuration() > time.Hour {
+		t.Errorf("Client.GetTokenInfo() = %+v", info)
+	}
+	if _, err = client.RawRequest("POST", "/v1/auth/token/renew-self", map[string]string{}); err != nil {
+		t.Errorf("renew-self error = %v", err)
+	}
+
+	cfg.AppRoleCredentials.SecretID = "wrong"
+	if _, err = vault.NewClient(cfg); statusCode(err) != http.StatusBadRequest {
+		t.Errorf("vaultlib.NewClient() wrong secret id error = %v, want a 400", err)
+	}
+
+	expiring := srv.CreateToken(time.Millisecond, "app")
+	time.Sleep(5 * time.Millisecond)
+	cfg = vault.NewConfig()
+	cfg.Address = srv.URL
+	cfg.Token = expiring
+	if _, err = vault.NewClient(cfg); statusCode(err) != http.StatusForbidden {
+		t.Errorf("vaultlib.NewClient() expired token error = %v, want a 403", err)
+	}
+
+	if err = client.RevokeSelf(); err != nil {
+		t.Errorf("Client.RevokeSelf() error = %v", err)
+	}
+	if _, err = client.GetSecret("secret/app"); statusCode(err) != http.StatusForbidden {
+		t.Errorf("Client.GetSecret() revoked token error = %v, want a 403", err)
+	}
+}
+
+func TestServer_Namespace(t *testing.T) {
+	srv := vaulttest.NewServer()
+	defer srv.Close()
+	ns := srv.Namespace("ns1/team")
+	ns.MountKV("kv", 2)
+	ns.PutSecret("kv/app", map[string]interface{}{"key": "team"})
+
+	client := newClient(t, srv, vaulttest.RootToken, "ns1/team")
+	if secret, err := client.GetSecret("kv/app"); err != nil || secret.KV["key"] != "team" {
+		t.Errorf("Client.GetSecret() namespace = %v, %v", secret.KV, err)
+	}
+	// the namespace may also prefix the path
+	client = newClient(t, srv, vaulttest.RootToken, "ns1")
+	if _, err := client.RawRequest("GET", "/v1/team/kv/data/app", nil); err != nil {
+		t.Errorf("RawRequest() namespace path error = %v", err)
+	}
+	client = newClient(t, srv, vaulttest.RootToken, "")
+	if _, err := client.GetSecret("kv/app"); err == nil {
+		t.Errorf("Client.GetSecret() expected error for a secret of another namespace")
+	}
+}
+
+func TestServer_Hooks(t *testing.T) {
+	srv := vaulttest.NewServer()
+	defer srv.Close()
+	srv.PutSecret("secret/app", map[string]interface{}{"key": "value"})
+	client := newClient(t, srv, vaulttest.RootToken, "")
+
+	srv.SetSealed(true)
+	if _, err := client.GetSecret("secret/app"); statusCode(err) != http.StatusServiceUnavailable {
+		t.Errorf("Client.GetSecret() sealed error = %v, want a 503", err)
+	}
+	srv.SetSealed(false)
+
+	srv.SetErrorHook(func(r *http.Request) int {
+		if r.URL.Path == "/v1/secret/data/app" {
+			return http.StatusInternalServerError
+		}
+		return 0
+	})
+	if _, err := client.GetSecret("secret/app"); statusCode(err) != http.StatusInternalServerError {
+		t.Errorf("Client.GetSecret() error hook error = %v, want a 500", err)
+	}
+	srv.SetErrorHook(nil)
+
+	srv.HandleFunc("sys/leases/", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		_, _ = w.Write([]byte(`{"data":` + string(body) + `}`))
+	})
+	if rsp, err := client.RawRequest("PUT", "/v1/sys/leases/renew", map[string]string{"lease_id": "abcd"}); err != nil || !strings.Contains(string(rsp), "abcd") {
+		t.Errorf("RawRequest() handler = %v, %v", rsp, err)
+	}
+	if _, err := client.RawRequest("PUT", "/v1/sys/other", nil); statusCode(err) != http.StatusNotFound {
+		t.Errorf("RawRequest() path without handler error = %v, want a 404", err)
+	}
+
+	srv.SetLatency(50 * time.Millisecond)
+	start := time.Now()
+	if _, err := client.GetSecret("secret/app"); err != nil || time.Since(start) < 50*time.Millisecond {
+		t.Errorf("Client.GetSecret() latency = %v, %v", time.Since(start), err)
+	}
+
+	requests := srv.Requests()
+	if len(requests) == 0 || requests[len(requests)-1] != "GET /v1/secret/data/app" {
+		t.Errorf("Server.Requests() = %v", requests)
+	}
+}
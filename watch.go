@@ -0,0 +1,155 @@
+package vaultlib
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// watchSecretMaxBackoff is the maximum delay between two polls after Vault errors
+var watchSecretMaxBackoff = 5 * time.Minute
+
+// secretWatch holds the state of a watched secret
+type secretWatch struct {
+	client    *Client
+	path      string
+	kvVersion string
+	kvName    string
+	version   int
+	deleted   bool
+	hash      [sha256.Size]byte
+	seen      bool
+}
+
+// WatchSecret polls the kv secret every interval and calls onChange with the secret
+// on the first read then each time it changes, until ctx is done. Returns the ctx error.
+//
+// kv v2 secrets are polled through their metadata (current_version), the secret is read
+// only when a new version is available. When the current kv v2 version is deleted or destroyed,
+// onChange is called once with an empty Secret. kv v1 secrets are read and compared by content.
+//
+// Vault errors are reported in the client status (GetStatus) and the polling interval is
+// doubled after each error, up to 5 minutes. onChange is called from the polling go routine.
+func (c *Client) WatchSecret(ctx context.Context, path string, interval time.Duration, onChange func(Secret)) error {
+	if interval <= 0 || onChange == nil {
+		return errors.New("A positive interval and a callback must be specified")
+	}
+	w := &secretWatch{client: c, path: path}
+	wait := time.Duration(0)
+
+	for {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		secret, changed, err := w.poll()
+		if err != nil {
+			c.setStatus("Error watching secret " + path + " " + err.Error())
+			wait = 2 * wait
+			if wait > watchSecretMaxBackoff {
+				wait = watchSecretMaxBackoff
+			}
+			if wait < interval {
+				wait = interval
+			}
+			continue
+		}
+		wait = interval
+		if changed {
+			onChange(secret)
+		}
+	}
+}
+
+// poll returns the secret and true if it changed since the previous poll
+func (w *secretWatch) poll() (Secret, bool, error) {
+	if w.kvVersion == "" {
+		kvVersion, kvName, err := w.client.getKVInfo(w.path)
+		if err != nil {
+			return Secret{}, false, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		w.kvVersion, w.kvName = kvVersion, kvName
+	}
+
+	if w.kvVersion == "2" {
+		version, deleted, err := w.currentVersion()
+		if err != nil {
+			return Secret{}, false, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		if w.seen && version == w.version && deleted == w.deleted {
+			return Secret{}, false, nil
+		}
+		// a deleted or destroyed version can not be read
+		if deleted {
+			w.version, w.deleted, w.seen = version, true, true
+			return Secret{}, true, nil
+		}
+		secret, err := w.client.readSecret(w.path, version)
+		if err != nil {
+			return Secret{}, false, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		w.version, w.deleted, w.seen = version, false, true
+		return secret, true, nil
+	}
+
+	secret, err := w.client.readSecret(w.path, 0)
+	if err != nil {
+		return Secret{}, false, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	hash := secretHash(secret)
+	if w.seen && hash == w.hash {
+		return Secret{}, false, nil
+	}
+	w.hash, w.seen = hash, true
+	return secret, true, nil
+}
+
+// currentVersion returns the current version of the kv v2 secret from its metadata,
+// and true if this version is deleted or destroyed
+func (w *secretWatch) currentVersion() (int, bool, error) {
+	var metadata kvMetadata
+	path := w.kvName + "metadata/" + strings.TrimPrefix(w.path, w.kvName)
+	if err := w.client.requestData("GET", path, nil, &metadata); err != nil {
+		return 0, false, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if metadata.CurrentVersion == 0 {
+		return 0, false, errors.New("No version found for secret " + w.path)
+	}
+	current := metadata.Versions[strconv.Itoa(metadata.CurrentVersion)]
+	return metadata.CurrentVersion, current.DeletionTime != "" || current.Destroyed, nil
+}
+
+// secretHash returns a hash of the secret content
+func secretHash(secret Secret) [sha256.Size]byte {
+	keys := make([]string, 0, len(secret.KV))
+	for k := range secret.KV {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	// values are length prefixed so that different secrets can not have the same encoding
+	h := sha256.New()
+	size := make([]byte, 8)
+	for _, k := range keys {
+		for _, v := range []string{k, secret.KV[k]} {
+			binary.BigEndian.PutUint64(size, uint64(len(v)))
+			h.Write(size)
+			h.Write([]byte(v))
+		}
+	}
+	h.Write(secret.JSONSecret)
+
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
@@ -0,0 +1,120 @@
+package vaultlib
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_WatchSecret(t *testing.T) {
+	var v2Reads, failures int32
+	srv, vc := newTestVault(t)
+	srv.PutSecret("kv_v2/my-secret", map[string]interface{}{"version": "1"})
+	srv.PutSecret("kv_v1/my-secret", map[string]interface{}{"value": "0"})
+	srv.SetErrorHook(func(r *http.Request) int {
+		if atomic.LoadInt32(&failures) > 0 {
+			atomic.AddInt32(&failures, -1)
+			return http.StatusServiceUnavailable
+		}
+		if r.URL.Path == "/v1/kv_v2/data/my-secret" {
+			atomic.AddInt32(&v2Reads, 1)
+		}
+		return 0
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		key    string
+		update func()
+	}{
+		{"kvV2", "kv_v2/my-secret", "version", func() { srv.PutSecret("kv_v2/my-secret", map[string]interface{}{"version": "2"}) }},
+		{"kvV1", "kv_v1/my-secret", "value", func() { srv.PutSecret("kv_v1/my-secret", map[string]interface{}{"value": "1"}) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			changes := make(chan Secret, 10)
+			done := make(chan error)
+			go func() {
+				done <- vc.WatchSecret(ctx, tt.path, 10*time.Millisecond, func(s Secret) { changes <- s })
+			}()
+
+			first := <-changes
+			time.Sleep(50 * time.Millisecond)
+			if len(changes) != 0 {
+				t.Errorf("WatchSecret() called back without change")
+			}
+
+			// errors are retried
+			atomic.StoreInt32(&failures, 2)
+			tt.update()
+			select {
+			case second := <-changes:
+				if second.KV[tt.key] == first.KV[tt.key] {
+					t.Errorf("WatchSecret() second = %v, first %v", second.KV, first.KV)
+				}
+			case <-time.After(time.Second):
+				t.Errorf("WatchSecret() change not delivered")
+			}
+
+			cancel()
+			if err := <-done; err != context.Canceled {
+				t.Errorf("WatchSecret() error = %v, want %v", err, context.Canceled)
+			}
+		})
+	}
+	if reads := atomic.LoadInt32(&v2Reads); reads != 2 {
+		t.Errorf("WatchSecret() kv v2 reads = %v, want 2 (only on new versions)", reads)
+	}
+
+	// the deletion of the current kv v2 version is reported once, with an empty secret
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan Secret, 10)
+	go func() {
+		_ = vc.WatchSecret(ctx, "kv_v2/my-secret", 10*time.Millisecond, func(s Secret) { changes <- s })
+	}()
+	<-changes
+	if err := vc.DeleteSecret("kv_v2/my-secret"); err != nil {
+		t.Fatalf("Client.DeleteSecret() error = %v", err)
+	}
+	select {
+	case deleted := <-changes:
+		if deleted.KV != nil || deleted.JSONSecret != nil {
+			t.Errorf("WatchSecret() deleted = %v, want an empty secret", deleted)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("WatchSecret() deletion not delivered")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if len(changes) != 0 {
+		t.Errorf("WatchSecret() deletion reported more than once")
+	}
+
+	if err := vc.WatchSecret(context.Background(), "kv_v1/my-secret", 0, func(Secret) {}); err == nil {
+		t.Errorf("WatchSecret() expected error for zero interval")
+	}
+}
+
+func Test_secretHash(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Secret
+		want bool
+	}{
+		{"same", Secret{KV: map[string]string{"a": "1", "b": "2"}}, Secret{KV: map[string]string{"b": "2", "a": "1"}}, true},
+		{"value", Secret{KV: map[string]string{"a": "1"}}, Secret{KV: map[string]string{"a": "2"}}, false},
+		{"boundary", Secret{KV: map[string]string{"ab": "c"}}, Secret{KV: map[string]string{"a": "bc"}}, false},
+		{"json", Secret{JSONSecret: []byte(`{"a":1}`)}, Secret{JSONSecret: []byte(`{"a":2}`)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := secretHash(tt.a) == secretHash(tt.b); got != tt.want {
+				t.Errorf("secretHash() equal = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
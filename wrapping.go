@@ -0,0 +1,275 @@
+package vaultlib
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// WrapInfo holds the information of a response wrapping token.
+//
+// CreationPath is the Vault path whose response was wrapped (ie "secret/data/my-secret").
+//
+// Accessor and WrappedAccessor are not returned by LookupWrapping.
+type WrapInfo struct {
+	Token           string    `json:"token"`
+	Accessor        string    `json:"accessor"`
+	TTL             int       `json:"ttl"`
+	CreationTime    time.Time `json:"creation_time"`
+	CreationPath    string    `json:"creation_path"`
+	WrappedAccessor string    `json:"wrapped_accessor"`
+}
+
+// UnwrappedResponse holds the response unwrapped from a wrapping token.
+//
+// Data holds the secret data, Auth the authentication information (ie for a wrapped token creation).
+//
+// CreationPath is the path whose response was wrapped, set only when verified by Unwrap.
+type UnwrappedResponse struct {
+	Data         json.RawMessage
+	Auth         json.RawMessage
+	Lease        Lease
+	CreationPath string
+}
+
+// TTLDuration returns the wrapping token TTL as a time.Duration
+func (w *WrapInfo) TTLDuration() time.Duration {
+	return time.Duration(w.TTL) * time.Second
+}
+
+// ExpireTime returns the wrapping token expiration time
+func (w *WrapInfo) ExpireTime() time.Time {
+	return w.CreationTime.Add(w.TTLDuration())
+}
+
+// WrapRequest executes the request like RawRequest, Vault wraps the response in a
+// single use token valid for wrapTTL instead of returning it.
+//
+// Returns the wrapping token information, to be handed to the party calling Unwrap.
+func (c *Client) WrapRequest(method, path string, payload interface{}, wrapTTL time.Duration) (*WrapInfo, error) {
+	if len(method) == 0 || len(path) == 0 {
+		return nil, errors.New("Both method and path must be specified")
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	url := c.address.String() + path
+
+	req, err := c.newRequest(method, url)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	req.setWrapTTL(wrapTTL)
+	if payload != nil {
+		if err = req.setJSONBody(payload); err != nil {
+			return nil, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+	}
+
+	rsp, err := req.execute()
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	wrapInfo, err := wrapInfoFromResponse(rsp)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return wrapInfo, nil
+}
+
+// GetSecretWrapped reads the KV secret (v1 or v2) like GetSecret, the secret is returned
+// wrapped in a single use token valid for wrapTTL. Use UnwrapSecret to read it.
+func (c *Client) GetSecretWrapped(path string, wrapTTL time.Duration) (*WrapInfo, error) {
+	kvVersion, kvName, err := c.getKVInfo(path)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	wrapInfo, err := c.WrapRequest("GET", "/v1/"+kvAPIPath(kvVersion, kvName, path), nil, wrapTTL)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return wrapInfo, nil
+}
+
+// LookupWrapping returns the information of the wrapping token without consuming it
+func (c *Client) LookupWrapping(token string) (*WrapInfo, error) {
+	var lookup struct {
+		CreationPath string    `json:"creation_path"`
+		CreationTime time.Time `json:"creation_time"`
+		CreationTTL  int       `json:"creation_ttl"`
+	}
+	url := c.address.String() + "/v1/sys/wrapping/lookup"
+
+	req, err := c.newRequest("POST", url)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if err = req.setJSONBody(map[string]string{"token": token}); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	rsp, err := req.execute()
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if err = json.Unmarshal([]byte(rsp.Data), &lookup); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return &WrapInfo{
+		Token:        token,
+		TTL:          lookup.CreationTTL,
+		CreationTime: lookup.CreationTime,
+		CreationPath: lookup.CreationPath,
+	}, nil
+}
+
+// Unwrap consumes the wrapping token and returns the wrapped response.
+//
+// If creationPath is not empty, the token creation path is verified: a token wrapping the
+// response of another path has been tampered with or substituted, the response is discarded
+// and an error returned. creationPath must match exactly, or by prefix if it ends with "*"
+// (ie "database/creds/*").
+//
+// The wrapping token itself is used for authentication if the client has no token.
+func (c *Client) Unwrap(token, creationPath string) (*UnwrappedResponse, error) {
+	if creationPath == "" {
+		rsp, err := c.unwrap(token)
+		if err != nil {
+			return nil, errors.Wrap(errors.WithStack(err), errInfo())
+		}
+		return rsp, nil
+	}
+	rsp, err := c.unwrapWithCreationPath(token)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if !matchCreationPath(creationPath, rsp.CreationPath) {
+		return nil, errors.New("Wrapping token creation path " + rsp.CreationPath + " does not match " + creationPath)
+	}
+	return rsp, nil
+}
+
+// unwrapWithCreationPath consumes the wrapping token, returns the wrapped response along
+// with its creation path.
+//
+// Vault does not return the creation path when unwrapping, it is read from the token beforehand.
+// The token being single use, a response unwrapped after the lookup is the one looked up:
+// the unwrap fails if the token has been consumed (or replaced by Rewrap) in the meantime.
+func (c *Client) unwrapWithCreationPath(token string) (*UnwrappedResponse, error) {
+	wrapInfo, err := c.LookupWrapping(token)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	rsp, err := c.unwrap(token)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	rsp.CreationPath = wrapInfo.CreationPath
+	return rsp, nil
+}
+
+// unwrap consumes the wrapping token and returns the wrapped response
+func (c *Client) unwrap(token string) (*UnwrappedResponse, error) {
+	url := c.address.String() + "/v1/sys/wrapping/unwrap"
+
+	req, err := c.newRequest("POST", url)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if c.getTokenID() == "" {
+		req.Req.Header.Set("X-Vault-Token", token)
+	} else if err = req.setJSONBody(map[string]string{"token": token}); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	rsp, err := req.execute()
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return &UnwrappedResponse{
+		Data:  rsp.Data,
+		Auth:  rsp.Auth,
+		Lease: leaseFromResponse(rsp),
+	}, nil
+}
+
+// UnwrapSecret consumes the wrapping token of a KV secret (see GetSecretWrapped)
+// and returns the secret. creationPath is verified as in Unwrap.
+//
+// The KV version is the one of the mount of the token creation path: the client
+// token must be allowed to list the secret mounts.
+func (c *Client) UnwrapSecret(token, creationPath string) (secret Secret, err error) {
+	secret.KV = make(map[string]string)
+
+	var rsp *UnwrappedResponse
+	if creationPath == "" {
+		rsp, err = c.unwrapWithCreationPath(token)
+	} else {
+		rsp, err = c.Unwrap(token, creationPath)
+	}
+	if err != nil {
+		return secret, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	secret.Lease = rsp.Lease
+
+	kvVersion, _, err := c.getKVInfo(rsp.CreationPath)
+	if err != nil {
+		return secret, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if err = parseSecretData(kvVersion, rsp.Data, &secret); err != nil {
+		return secret, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return secret, nil
+}
+
+// Rewrap exchanges the wrapping token for a new one wrapping the same response,
+// ie to extend its TTL. creationPath is verified as in Unwrap, on the new token.
+func (c *Client) Rewrap(token, creationPath string) (*WrapInfo, error) {
+	url := c.address.String() + "/v1/sys/wrapping/rewrap"
+
+	req, err := c.newRequest("POST", url)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if err = req.setJSONBody(map[string]string{"token": token}); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+
+	rsp, err := req.execute()
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	wrapInfo, err := wrapInfoFromResponse(rsp)
+	if err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	if creationPath != "" && !matchCreationPath(creationPath, wrapInfo.CreationPath) {
+		return nil, errors.New("Wrapping token creation path " + wrapInfo.CreationPath + " does not match " + creationPath)
+	}
+	return wrapInfo, nil
+}
+
+// matchCreationPath returns true if the creation path matches the expected one,
+// by prefix if expected ends with "*"
+func matchCreationPath(expected, creationPath string) bool {
+	expected = strings.TrimPrefix(expected, "/")
+	creationPath = strings.TrimPrefix(creationPath, "/")
+	if strings.HasSuffix(expected, "*") {
+		return strings.HasPrefix(creationPath, strings.TrimSuffix(expected, "*"))
+	}
+	return expected == creationPath
+}
+
+// wrapInfoFromResponse returns the wrap info of the Vault response
+func wrapInfoFromResponse(rsp vaultResponse) (*WrapInfo, error) {
+	var wrapInfo WrapInfo
+	if len(rsp.WrapInfo) == 0 || string(rsp.WrapInfo) == "null" {
+		return nil, errors.New("Vault response is not wrapped")
+	}
+	if err := json.Unmarshal([]byte(rsp.WrapInfo), &wrapInfo); err != nil {
+		return nil, errors.Wrap(errors.WithStack(err), errInfo())
+	}
+	return &wrapInfo, nil
+}
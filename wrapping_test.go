@@ -0,0 +1,116 @@
+package vaultlib
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClient_WrapUnwrap(t *testing.T) {
+	conf := NewConfig()
+	conf.Token = "my-dev-root-vault-token"
+	vc, _ := NewClient(conf)
+
+	tests := []struct {
+		name         string
+		path         string
+		creationPath string
+		wantKey      string
+		wantErr      bool
+	}{
+		{"kvV2", "kv_v2/path/my-secret", "kv_v2/path/data/my-secret", "my-first-secret", false},
+		{"kvV1", "kv_v1/path/my-secret", "kv_v1/path/*", "my-v1-secret", false},
+		{"tampered", "kv_v2/path/my-secret", "kv_v1/path/my-secret", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wrapInfo, err := vc.GetSecretWrapped(tt.path, time.Minute)
+			if err != nil {
+				t.Fatalf("Client.GetSecretWrapped() error = %v", err)
+			}
+			if wrapInfo.Token == "" || wrapInfo.TTL != 60 || wrapInfo.CreationPath == "" {
+				t.Errorf("Client.GetSecretWrapped() = %+v", wrapInfo)
+			}
+
+			got, err := vc.UnwrapSecret(wrapInfo.Token, tt.creationPath)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Client.UnwrapSecret() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got.KV[tt.wantKey] == "" {
+				t.Errorf("Client.UnwrapSecret() = %v, want key %v", got.KV, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestClient_LookupRewrap(t *testing.T) {
+	conf := NewConfig()
+	conf.Token = "my-dev-root-vault-token"
+	vc, _ := NewClient(conf)
+
+	wrapInfo, err := vc.WrapRequest("POST", "/v1/auth/token/create", map[string]interface{}{"policies": []string{"default"}}, time.Minute)
+	if err != nil {
+		t.Fatalf("Client.WrapRequest() error = %v", err)
+	}
+	lookup, err := vc.LookupWrapping(wrapInfo.Token)
+	if err != nil {
+		t.Fatalf("Client.LookupWrapping() error = %v", err)
+	}
+	if lookup.CreationPath != "auth/token/create" || lookup.TTL != 60 {
+		t.Errorf("Client.LookupWrapping() = %+v", lookup)
+	}
+
+	rewrapped, err := vc.Rewrap(wrapInfo.Token, "auth/token/create")
+	if err != nil {
+		t.Fatalf("Client.Rewrap() error = %v", err)
+	}
+	if _, err = vc.Unwrap(wrapInfo.Token, ""); err == nil {
+		t.Errorf("Client.Unwrap() expected error for the token replaced by Rewrap")
+	}
+	got, err := vc.Unwrap(rewrapped.Token, "auth/token/create")
+	if err != nil {
+		t.Fatalf("Client.Unwrap() error = %v", err)
+	}
+	if len(got.Auth) == 0 {
+		t.Errorf("Client.Unwrap() expected auth, got %+v", got)
+	}
+}
+
+func TestClient_UnwrapSecretKVVersion(t *testing.T) {
+	// a kv v1 secret holding "data" and "metadata" keys is not mistaken for a kv v2 one
+	srv, vc := newTestVault(t)
+	srv.HandleFunc("sys/wrapping/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/sys/wrapping/lookup":
+			_, _ = w.Write([]byte(`{"data":{"creation_path":"kv_v1/app","creation_time":"2020-01-02T15:04:05Z","creation_ttl":300}}`))
+		case "/v1/sys/wrapping/unwrap":
+			_, _ = w.Write([]byte(`{"data":{"data":"some data","metadata":"some metadata"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	got, err := vc.UnwrapSecret("s.wrap", "")
+	if err != nil || got.KV["data"] != "some data" || got.KV["metadata"] != "some metadata" {
+		t.Errorf("Client.UnwrapSecret() = %v, %v", got.KV, err)
+	}
+}
+
+func Test_matchCreationPath(t *testing.T) {
+	tests := []struct {
+		expected     string
+		creationPath string
+		want         bool
+	}{
+		{"secret/data/my-secret", "secret/data/my-secret", true},
+		{"/secret/data/my-secret", "secret/data/my-secret", true},
+		{"secret/data/my-secret", "secret/data/my-secret2", false},
+		{"secret/data/*", "secret/data/my-secret", true},
+		{"secret/data/*", "other/data/my-secret", false},
+	}
+	for _, tt := range tests {
+		if got := matchCreationPath(tt.expected, tt.creationPath); got != tt.want {
+			t.Errorf("matchCreationPath(%v, %v) = %v, want %v", tt.expected, tt.creationPath, got, tt.want)
+		}
+	}
+}